@@ -0,0 +1,92 @@
+package frostutil
+
+import "image"
+
+// AlphaBleed fills in the color components of img's fully transparent pixels from their nearest non-transparent neighbors, without changing any alpha components.
+// Each iteration grows the filled area by one pixel: every fully transparent pixel which touches (including diagonally) a pixel which is either
+// non-transparent or was filled in a previous iteration gets the average color of those neighbors. If iterations is <= 0, it keeps going until
+// every fully transparent pixel has been filled (unless img has no non-transparent pixels at all, in which case nothing changes).
+// Fully transparent pixels which are too far away to be reached keep their colors.
+//
+// This keeps bilinear filtering and mipmapping from pulling the (usually black) colors of transparent pixels into the edges of sprites,
+// for anything that samples straight alpha colors. Note that since *ebiten.Images are alpha-premultiplied, NewEImageFromImage stores fully
+// transparent pixels as transparent black regardless, which premultiplied filtering already handles correctly; the bled colors are kept
+// by SavePNGPreserveColors, EncodeQOI, EncodeTGA, Resize with FilterNearest, and anything else that preserves colors where alpha is zero.
+func AlphaBleed(img *image.NRGBA, iterations int) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// filled is true for every pixel whose color can be used by its neighbors
+	filled := make([]bool, width*height)
+	var frontier []int
+	for y := 0; y < height; y++ {
+		idx := y * img.Stride
+		for x := 0; x < width; x++ {
+			if img.Pix[idx+3] != 0 {
+				filled[y*width+x] = true
+			}
+			idx += 4
+		}
+	}
+	// the first frontier is every transparent pixel next to a non-transparent one
+	for i := range filled {
+		if !filled[i] && hasFilledNeighbor(filled, i%width, i/width, width, height) {
+			frontier = append(frontier, i)
+		}
+	}
+
+	var next []int
+	queued := make([]bool, width*height)
+	colors := make([][3]byte, 0, len(frontier))
+	for iteration := 0; len(frontier) > 0 && (iterations <= 0 || iteration < iterations); iteration++ {
+		// Work out every color before writing any of them, so that the pixels filled in this iteration don't affect each other.
+		colors = colors[:0]
+		for _, i := range frontier {
+			x, y := i%width, i/width
+			var r, g, b, count int
+			for ny := Max(y-1, 0); ny <= Min(y+1, height-1); ny++ {
+				for nx := Max(x-1, 0); nx <= Min(x+1, width-1); nx++ {
+					if filled[ny*width+nx] {
+						idx := ny*img.Stride + nx*4
+						r += int(img.Pix[idx])
+						g += int(img.Pix[idx+1])
+						b += int(img.Pix[idx+2])
+						count++
+					}
+				}
+			}
+			colors = append(colors, [3]byte{byte((r + count/2) / count), byte((g + count/2) / count), byte((b + count/2) / count)})
+		}
+		for n, i := range frontier {
+			idx := (i/width)*img.Stride + (i%width)*4
+			img.Pix[idx], img.Pix[idx+1], img.Pix[idx+2] = colors[n][0], colors[n][1], colors[n][2]
+			filled[i] = true
+		}
+		// the next frontier is every unfilled pixel next to one we just filled
+		next = next[:0]
+		for _, i := range frontier {
+			x, y := i%width, i/width
+			for ny := Max(y-1, 0); ny <= Min(y+1, height-1); ny++ {
+				for nx := Max(x-1, 0); nx <= Min(x+1, width-1); nx++ {
+					nIdx := ny*width + nx
+					if !filled[nIdx] && !queued[nIdx] {
+						queued[nIdx] = true
+						next = append(next, nIdx)
+					}
+				}
+			}
+		}
+		frontier, next = next, frontier
+	}
+}
+
+// hasFilledNeighbor returns true if any of the (up to) eight pixels around (x, y) are filled.
+func hasFilledNeighbor(filled []bool, x, y, width, height int) bool {
+	for ny := Max(y-1, 0); ny <= Min(y+1, height-1); ny++ {
+		for nx := Max(x-1, 0); nx <= Min(x+1, width-1); nx++ {
+			if filled[ny*width+nx] {
+				return true
+			}
+		}
+	}
+	return false
+}
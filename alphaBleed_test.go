@@ -0,0 +1,58 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AlphaBleed(t *testing.T) {
+	ass := assert.New(t)
+	// an opaque red pixel at (1, 1) and a half transparent blue pixel at (5, 1)
+	newSprite := func() *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, 7, 7))
+		img.SetNRGBA(1, 1, color.NRGBA{R: 255, A: 255})
+		img.SetNRGBA(5, 1, color.NRGBA{B: 200, A: 128})
+		return img
+	}
+	img := newSprite()
+	frostutil.AlphaBleed(img, 1)
+	// the neighbors of the red pixel are red, and the neighbors of the blue pixel are blue
+	ass.Equal(color.NRGBA{R: 255}, img.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{R: 255}, img.NRGBAAt(2, 2))
+	ass.Equal(color.NRGBA{B: 200}, img.NRGBAAt(6, 2))
+	// (3, 1) is two pixels from each, so it's out of reach after one iteration
+	ass.Equal(color.NRGBA{}, img.NRGBAAt(3, 1))
+	// the sources are untouched
+	ass.Equal(color.NRGBA{R: 255, A: 255}, img.NRGBAAt(1, 1))
+	ass.Equal(color.NRGBA{B: 200, A: 128}, img.NRGBAAt(5, 1))
+
+	frostutil.AlphaBleed(img, 0)
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 7; x++ {
+			c := img.NRGBAAt(x, y)
+			if x != 1 && x != 5 || y != 1 {
+				ass.Zero(c.A, "alpha changed at (%v, %v)", x, y)
+			}
+			ass.NotEqual(color.NRGBA{}, c, "(%v, %v) wasn't filled", x, y)
+		}
+	}
+	// halfway between them, the colors are mixed
+	ass.Equal(color.NRGBA{R: 128, B: 100}, img.NRGBAAt(3, 1))
+
+	// nothing to bleed from
+	empty := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	empty.SetNRGBA(1, 1, color.NRGBA{G: 5})
+	frostutil.AlphaBleed(empty, 0)
+	ass.Equal(color.NRGBA{}, empty.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{G: 5}, empty.NRGBAAt(1, 1))
+
+	// sub-images only bleed inside their bounds
+	img = newSprite()
+	frostutil.AlphaBleed(img.SubImage(image.Rect(0, 0, 4, 4)).(*image.NRGBA), 0)
+	ass.Equal(color.NRGBA{R: 255}, img.NRGBAAt(3, 3))
+	ass.Equal(color.NRGBA{}, img.NRGBAAt(4, 1))
+}
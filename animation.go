@@ -0,0 +1,395 @@
+package frostutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// This file encodes sequences of frames as animated GIFs and APNGs, for bug reports, trailers, and visual test artifacts.
+// Frames can be *ebiten.Images (which are read with NewImageFromEImage) or any other image.Image. The animation is as big as the largest frame,
+// and each frame is drawn at the top-left corner, replacing the previous one, with transparency wherever it doesn't reach.
+
+// AnimationFrame is one frame of an animation: an image, and how long it's shown for.
+type AnimationFrame struct {
+	Image image.Image
+	Delay time.Duration
+}
+
+// GIFOptions controls EncodeGIF.
+type GIFOptions struct {
+	// NumColors is the most colors each frame's palette can have (each frame gets its own), including the transparent color if the frame needs one.
+	// Palettes are chosen with median cut quantization. If it's <= 0 or > 256, 256 is used, and if it's 1, 2 is used.
+	NumColors int
+	Dither    bool // If true, colors are dithered with Floyd-Steinberg error diffusion, which avoids banding in gradients.
+	// GIFs don't have partial transparency, so pixels with alpha greater than AlphaThreshold are drawn as opaque, and the rest are transparent.
+	AlphaThreshold byte
+	Plays          int // How many times the animation plays. If it's 0, it loops forever.
+}
+
+// APNGOptions controls EncodeAPNG.
+type APNGOptions struct {
+	Plays int // How many times the animation plays. If it's 0, it loops forever.
+}
+
+// errNoFrames is returned (wrapped in an *ImageEncodeError) when asked to encode an animation with no frames.
+var errNoFrames = errors.New("no frames to encode")
+
+// animationCanvas converts frames' images to *image.NRGBAs with their top-left corners at (0, 0), and returns them with the size of the largest one.
+func animationCanvas(frames []AnimationFrame) (images []*image.NRGBA, size image.Point) {
+	images = make([]*image.NRGBA, len(frames))
+	for i, frame := range frames {
+		img := frame.Image
+		if eImg, ok := img.(*ebiten.Image); ok {
+			img = NewImageFromEImage(eImg)
+		}
+		images[i] = NewNRGBAFromImage(img)
+		size.X, size.Y = Max(size.X, images[i].Rect.Dx()), Max(size.Y, images[i].Rect.Dy())
+	}
+	return
+}
+
+// EncodeGIF encodes frames to w as an animated GIF, converting each frame to a palette of up to opts.NumColors colors. opts may be nil.
+// GIF delays are in hundredths of a second, so each frame's delay is rounded to the nearest one.
+// If there are no frames, or encoding fails, it returns an *ImageEncodeError.
+func EncodeGIF(w io.Writer, frames []AnimationFrame, opts *GIFOptions) error {
+	return encodeGIF(w, frames, opts, "")
+}
+
+// encodeGIF implements EncodeGIF. name is only used in errors.
+func encodeGIF(w io.Writer, frames []AnimationFrame, opts *GIFOptions, name string) (err error) {
+	if opts == nil {
+		opts = &GIFOptions{}
+	}
+	if len(frames) == 0 {
+		return &ImageEncodeError{Name: name, Format: ImageFormatGIF, Err: errNoFrames}
+	}
+	numColors := opts.NumColors
+	if numColors <= 0 || numColors > 256 {
+		numColors = 256
+	}
+	numColors = Max(numColors, 2)
+	images, size := animationCanvas(frames)
+	// gif.GIF's LoopCount is how many times the animation repeats after playing once, with 0 meaning forever and -1 meaning no repeats
+	anim := &gif.GIF{Config: image.Config{Width: size.X, Height: size.Y}}
+	if opts.Plays == 1 {
+		anim.LoopCount = -1
+	} else if opts.Plays > 1 {
+		anim.LoopCount = opts.Plays - 1
+	}
+	bounds := image.Rect(0, 0, size.X, size.Y)
+	for i, img := range images {
+		// Make each pixel either opaque or transparent, and count the opaque colors.
+		rgba := image.NewRGBA(bounds)
+		counts := map[color.RGBA]int{}
+		transparent := img.Rect.Dx() < size.X || img.Rect.Dy() < size.Y
+		for y := 0; y < img.Rect.Dy(); y++ {
+			for x := 0; x < img.Rect.Dx(); x++ {
+				p := img.Pix[y*img.Stride+x*4 : y*img.Stride+x*4+4]
+				if p[3] <= opts.AlphaThreshold {
+					transparent = true
+					continue
+				}
+				c := color.RGBA{p[0], p[1], p[2], 0xff}
+				rgba.SetRGBA(x, y, c)
+				counts[c]++
+			}
+		}
+		maxColors := numColors
+		if transparent {
+			maxColors--
+		}
+		palette := medianCutPalette(counts, maxColors)
+		if transparent {
+			palette = append(palette, color.RGBA{})
+		}
+		paletted := image.NewPaletted(bounds, palette)
+		if opts.Dither {
+			draw.FloydSteinberg.Draw(paletted, bounds, rgba, image.Point{})
+		} else {
+			draw.Draw(paletted, bounds, rgba, image.Point{}, draw.Src)
+		}
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, int((frames[i].Delay+5*time.Millisecond)/(10*time.Millisecond)))
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+	if err = gif.EncodeAll(w, anim); err != nil {
+		err = &ImageEncodeError{Name: name, Format: ImageFormatGIF, Err: err}
+	}
+	return
+}
+
+// medianCutPalette picks up to n colors to represent the colors in counts (weighted by how many pixels have them) with median cut:
+// starting with a box around all the colors, it keeps splitting the box with the widest range of any component at its weighted median,
+// and then uses the average color of each box. If there are n or fewer colors, they're all used as is.
+func medianCutPalette(counts map[color.RGBA]int, n int) color.Palette {
+	colors := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	// sorted so the palette doesn't depend on map order
+	sort.Slice(colors, func(i, j int) bool {
+		ci, cj := colors[i], colors[j]
+		if ci.R != cj.R {
+			return ci.R < cj.R
+		} else if ci.G != cj.G {
+			return ci.G < cj.G
+		}
+		return ci.B < cj.B
+	})
+	palette := make(color.Palette, 0, n)
+	if len(colors) <= n {
+		for _, c := range colors {
+			palette = append(palette, c)
+		}
+		return palette
+	}
+
+	component := func(c color.RGBA, i int) byte {
+		return [3]byte{c.R, c.G, c.B}[i]
+	}
+	// widest returns which component has the widest range in box, and how wide it is.
+	widest := func(box []color.RGBA) (channel int, width int) {
+		for i := 0; i < 3; i++ {
+			lo, hi := byte(0xff), byte(0)
+			for _, c := range box {
+				lo, hi = Min(lo, component(c, i)), Max(hi, component(c, i))
+			}
+			if int(hi)-int(lo) > width {
+				channel, width = i, int(hi)-int(lo)
+			}
+		}
+		return
+	}
+	boxes := [][]color.RGBA{colors}
+	for len(boxes) < n {
+		best, bestChannel, bestWidth := -1, 0, 0
+		for i, box := range boxes {
+			if channel, width := widest(box); width > bestWidth {
+				best, bestChannel, bestWidth = i, channel, width
+			}
+		}
+		if best < 0 {
+			break
+		}
+		box := boxes[best]
+		sort.SliceStable(box, func(i, j int) bool { return component(box[i], bestChannel) < component(box[j], bestChannel) })
+		total := 0
+		for _, c := range box {
+			total += counts[c]
+		}
+		// split after the weighted median, keeping at least one color on each side
+		split, seen := 1, counts[box[0]]
+		for split < len(box)-1 && seen*2 < total {
+			seen += counts[box[split]]
+			split++
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+	for _, box := range boxes {
+		var r, g, b, total int
+		for _, c := range box {
+			count := counts[c]
+			r, g, b, total = r+int(c.R)*count, g+int(c.G)*count, b+int(c.B)*count, total+count
+		}
+		palette = append(palette, color.RGBA{byte((r + total/2) / total), byte((g + total/2) / total), byte((b + total/2) / total), 0xff})
+	}
+	return palette
+}
+
+// EncodeAPNG encodes frames to w as a lossless animated PNG, with 8-bit RGBA pixels (with the colors of transparent pixels preserved, like
+// EncodePNGPreserveColors). opts may be nil. Viewers that don't support APNG show the first frame.
+// Each frame's delay is stored in milliseconds, up to 65.535 seconds.
+// If there are no frames, or encoding fails, it returns an *ImageEncodeError.
+func EncodeAPNG(w io.Writer, frames []AnimationFrame, opts *APNGOptions) error {
+	return encodeAPNG(w, frames, opts, "")
+}
+
+// encodeAPNG implements EncodeAPNG. name is only used in errors.
+func encodeAPNG(w io.Writer, frames []AnimationFrame, opts *APNGOptions, name string) (err error) {
+	if opts == nil {
+		opts = &APNGOptions{}
+	}
+	if len(frames) == 0 {
+		return &ImageEncodeError{Name: name, Format: ImageFormatPNG, Err: errNoFrames}
+	}
+	images, size := animationCanvas(frames)
+	pw := &pngChunkWriter{w: w}
+	pw.write([]byte("\x89PNG\r\n\x1a\n"))
+	// 8 bits per component, color type 6 (RGBA), default compression and filtering, no interlacing
+	pw.chunk("IHDR", binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(size.X)), uint32(size.Y)), 8, 6, 0, 0, 0)
+	pw.chunk("acTL", binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(len(frames))), uint32(Max(opts.Plays, 0))))
+	var sequence uint32
+	var data bytes.Buffer
+	for i, img := range images {
+		delay := Min(Max(frames[i].Delay.Milliseconds(), 0), 0xffff)
+		// sequence number, width, height, x and y offsets, delay numerator and denominator, and dispose and blend ops (none, and source)
+		fcTL := binary.BigEndian.AppendUint32(nil, sequence)
+		for _, v := range []uint32{uint32(size.X), uint32(size.Y), 0, 0} {
+			fcTL = binary.BigEndian.AppendUint32(fcTL, v)
+		}
+		fcTL = binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(fcTL, uint16(delay)), 1000)
+		pw.chunk("fcTL", fcTL, 0, 0)
+		sequence++
+
+		data.Reset()
+		if err = compressPNGImage(&data, img, size); err != nil {
+			break
+		}
+		if i == 0 {
+			pw.chunk("IDAT", data.Bytes())
+		} else {
+			pw.chunk("fdAT", binary.BigEndian.AppendUint32(nil, sequence), data.Bytes()...)
+			sequence++
+		}
+	}
+	pw.chunk("IEND", nil)
+	if err == nil {
+		err = pw.err
+	}
+	if err != nil {
+		err = &ImageEncodeError{Name: name, Format: ImageFormatPNG, Err: err}
+	}
+	return
+}
+
+// pngChunkWriter writes PNG chunks to w, remembering the first error so it can be checked once at the end.
+type pngChunkWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *pngChunkWriter) write(b []byte) {
+	if pw.err == nil {
+		_, pw.err = pw.w.Write(b)
+	}
+}
+
+// chunk writes a chunk with the given type, and data made of data followed by more.
+func (pw *pngChunkWriter) chunk(chunkType string, data []byte, more ...byte) {
+	data = append(data, more...)
+	header := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	header = append(header, chunkType...)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	pw.write(header)
+	pw.write(data)
+	pw.write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+}
+
+// compressPNGImage writes img's pixels, padded with transparent pixels to size, as zlib-compressed PNG scanlines, for an IDAT or fdAT chunk.
+// Each row uses whichever PNG filter gives the smallest sum of absolute values, the same heuristic the png package uses.
+func compressPNGImage(w io.Writer, img *image.NRGBA, size image.Point) error {
+	zw, err := zlib.NewWriterLevel(w, zlib.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	rowLen := size.X * 4
+	prev, cur := make([]byte, rowLen), make([]byte, rowLen)
+	var filtered [5][]byte
+	for i := range filtered {
+		filtered[i] = make([]byte, rowLen+1)
+		filtered[i][0] = byte(i)
+	}
+	for y := 0; y < size.Y; y++ {
+		for i := range cur {
+			cur[i] = 0
+		}
+		if y < img.Rect.Dy() {
+			copy(cur, img.Pix[y*img.Stride:y*img.Stride+img.Rect.Dx()*4])
+		}
+		best, bestSum := 0, -1
+		for f := range filtered {
+			out := filtered[f][1:]
+			sum := 0
+			for i, v := range cur {
+				var left, up, upLeft byte
+				if i >= 4 {
+					left, upLeft = cur[i-4], prev[i-4]
+				}
+				up = prev[i]
+				switch f {
+				case 0:
+					out[i] = v
+				case 1:
+					out[i] = v - left
+				case 2:
+					out[i] = v - up
+				case 3:
+					out[i] = v - byte((int(left)+int(up))/2)
+				case 4:
+					out[i] = v - paeth(left, up, upLeft)
+				}
+				sum += Abs(int(int8(out[i])))
+			}
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = f, sum
+			}
+		}
+		if _, err = zw.Write(filtered[best]); err != nil {
+			return err
+		}
+		prev, cur = cur, prev
+	}
+	return zw.Close()
+}
+
+// paeth is the PNG Paeth predictor: whichever of a (left), b (up), and c (up-left) is closest to a + b - c.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := Abs(p-int(a)), Abs(p-int(b)), Abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	} else if pb <= pc {
+		return b
+	}
+	return c
+}
+
+// SaveGIF writes frames to the file at path as an animated GIF with EncodeGIF, creating or truncating it.
+// If the file can't be created, the error from os.Create is returned as is, and if encoding fails, it returns an *ImageEncodeError.
+func SaveGIF(path string, frames []AnimationFrame, opts *GIFOptions) error {
+	return saveAnimation(path, ImageFormatGIF, func(w io.Writer) error { return encodeGIF(w, frames, opts, path) })
+}
+
+// SaveAPNG writes frames to the file at path as an animated PNG with EncodeAPNG, creating or truncating it.
+// If the file can't be created, the error from os.Create is returned as is, and if encoding fails, it returns an *ImageEncodeError.
+func SaveAPNG(path string, frames []AnimationFrame, opts *APNGOptions) error {
+	return saveAnimation(path, ImageFormatPNG, func(w io.Writer) error { return encodeAPNG(w, frames, opts, path) })
+}
+
+// saveAnimation creates the file at path and writes to it with encode, through a bufio.Writer.
+func saveAnimation(path string, format ImageFormat, encode func(io.Writer) error) (err error) {
+	fw, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := fw.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	w := bufio.NewWriter(fw)
+	if err = encode(w); err != nil {
+		return
+	}
+	if err = w.Flush(); err != nil {
+		err = &ImageEncodeError{Name: path, Format: format, Err: err}
+	}
+	return
+}
@@ -0,0 +1,271 @@
+package frostutil_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeAnimationFrames makes three small frames: a 4x3 red one with a transparent corner (whose color should be kept in APNGs),
+// a 4x3 translucent green one, and a 2x2 blue one, which is smaller than the others.
+func makeAnimationFrames() []frostutil.AnimationFrame {
+	red := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	green := image.NewNRGBA(image.Rect(10, 10, 14, 13))
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:], []byte{255, 0, 0, 255})
+		copy(green.Pix[i:], []byte{0, 255, 0, 100})
+	}
+	red.SetNRGBA(0, 0, color.NRGBA{10, 20, 30, 0})
+	blue := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < len(blue.Pix); i += 4 {
+		copy(blue.Pix[i:], []byte{0, 0, 255, 255})
+	}
+	return []frostutil.AnimationFrame{
+		{Image: red, Delay: 100 * time.Millisecond},
+		{Image: green, Delay: 250 * time.Millisecond},
+		{Image: blue, Delay: 33 * time.Millisecond},
+	}
+}
+
+func Test_EncodeGIF(t *testing.T) {
+	ass := assert.New(t)
+	frames := makeAnimationFrames()
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeGIF(&buf, frames, nil))
+	anim, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	ass.Equal(image.Point{4, 3}, image.Pt(anim.Config.Width, anim.Config.Height))
+	ass.Equal([]int{10, 25, 3}, anim.Delay)
+	ass.Equal(0, anim.LoopCount)
+	require.Len(t, anim.Image, 3)
+	ass.Equal(color.RGBA{255, 0, 0, 255}, color.RGBAModel.Convert(anim.Image[0].At(1, 1)))
+	ass.Equal(color.RGBA{}, color.RGBAModel.Convert(anim.Image[0].At(0, 0)))
+	// translucent pixels are opaque, with their unmultiplied color
+	ass.Equal(color.RGBA{0, 255, 0, 255}, color.RGBAModel.Convert(anim.Image[1].At(2, 2)))
+	// smaller frames are transparent past their edges
+	ass.Equal(color.RGBA{0, 0, 255, 255}, color.RGBAModel.Convert(anim.Image[2].At(1, 1)))
+	ass.Equal(color.RGBA{}, color.RGBAModel.Convert(anim.Image[2].At(3, 2)))
+
+	// with a higher alpha threshold, translucent pixels become transparent
+	buf.Reset()
+	require.NoError(t, frostutil.EncodeGIF(&buf, frames, &frostutil.GIFOptions{AlphaThreshold: 127, Plays: 1}))
+	anim, err = gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	ass.Equal(color.RGBA{}, color.RGBAModel.Convert(anim.Image[1].At(2, 2)))
+	ass.Equal(-1, anim.LoopCount)
+	buf.Reset()
+	require.NoError(t, frostutil.EncodeGIF(&buf, frames, &frostutil.GIFOptions{Plays: 3}))
+	anim, err = gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	ass.Equal(2, anim.LoopCount)
+
+	err = frostutil.EncodeGIF(&buf, nil, nil)
+	var encodeErr *frostutil.ImageEncodeError
+	if ass.True(errors.As(err, &encodeErr)) {
+		ass.Equal(frostutil.ImageFormatGIF, encodeErr.Format)
+	}
+}
+
+func Test_EncodeGIFQuantization(t *testing.T) {
+	ass := assert.New(t)
+	gradient := image.NewNRGBA(image.Rect(0, 0, 256, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 256; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{byte(x), byte(255 - x), byte(y * 16), 255})
+		}
+	}
+	frames := []frostutil.AnimationFrame{{Image: gradient}}
+
+	// blockError returns the total difference between the average red of each 16x16 block of the original and the GIF
+	blockError := func(img image.Image) (total int) {
+		for bx := 0; bx < 256; bx += 16 {
+			var sum0, sum1 int
+			for y := 0; y < 16; y++ {
+				for x := bx; x < bx+16; x++ {
+					r, _, _, _ := img.At(x, y).RGBA()
+					sum0 += int(gradient.NRGBAAt(x, y).R)
+					sum1 += int(r >> 8)
+				}
+			}
+			total += frostutil.Abs(sum0-sum1) / 256
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeGIF(&buf, frames, &frostutil.GIFOptions{NumColors: 8}))
+	anim, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	ass.LessOrEqual(len(anim.Image[0].Palette), 8)
+	plain := blockError(anim.Image[0])
+	// every pixel is near its original color
+	for x := 0; x < 256; x++ {
+		r, g, _, _ := anim.Image[0].At(x, 0).RGBA()
+		ass.InDelta(x, int(r>>8), 40, "%v", x)
+		ass.InDelta(255-x, int(g>>8), 40, "%v", x)
+	}
+
+	buf.Reset()
+	require.NoError(t, frostutil.EncodeGIF(&buf, frames, &frostutil.GIFOptions{NumColors: 8, Dither: true}))
+	anim, err = gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	ass.LessOrEqual(len(anim.Image[0].Palette), 8)
+	// dithering keeps the average colors of areas closer to the original
+	ass.Less(blockError(anim.Image[0]), plain)
+}
+
+// apngChunk is a chunk read back from a PNG file.
+type apngChunk struct {
+	chunkType string
+	data      []byte
+}
+
+// readPNGChunks splits PNG data into its chunks, checking their CRCs.
+func readPNGChunks(t *testing.T, data []byte) (chunks []apngChunk) {
+	require.Equal(t, "\x89PNG\r\n\x1a\n", string(data[:8]))
+	data = data[8:]
+	for len(data) > 0 {
+		length := binary.BigEndian.Uint32(data)
+		chunk := apngChunk{string(data[4:8]), data[8 : 8+length]}
+		require.Equal(t, crc32.ChecksumIEEE(data[4:8+length]), binary.BigEndian.Uint32(data[8+length:]), chunk.chunkType)
+		chunks = append(chunks, chunk)
+		data = data[12+length:]
+	}
+	return
+}
+
+// decodeAPNGFrames decodes each frame of an APNG made by EncodeAPNG (which are all the size of the whole image) by rebuilding them as regular PNGs.
+// It also returns the number of plays, and each frame's delay.
+func decodeAPNGFrames(t *testing.T, data []byte) (frames []image.Image, plays int, delays []time.Duration) {
+	chunks := readPNGChunks(t, data)
+	var ihdr []byte
+	var frameData [][]byte
+	sequence := uint32(0)
+	for _, chunk := range chunks {
+		switch chunk.chunkType {
+		case "IHDR":
+			ihdr = chunk.data
+		case "acTL":
+			plays = int(binary.BigEndian.Uint32(chunk.data[4:]))
+		case "fcTL":
+			require.Equal(t, sequence, binary.BigEndian.Uint32(chunk.data))
+			sequence++
+			num, den := binary.BigEndian.Uint16(chunk.data[20:]), binary.BigEndian.Uint16(chunk.data[22:])
+			delays = append(delays, time.Duration(num)*time.Second/time.Duration(den))
+			frameData = append(frameData, nil)
+		case "IDAT":
+			frameData[len(frameData)-1] = append(frameData[len(frameData)-1], chunk.data...)
+		case "fdAT":
+			require.Equal(t, sequence, binary.BigEndian.Uint32(chunk.data))
+			sequence++
+			frameData[len(frameData)-1] = append(frameData[len(frameData)-1], chunk.data[4:]...)
+		}
+	}
+	writeChunk := func(buf *bytes.Buffer, chunkType string, data []byte) {
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+		buf.WriteString(chunkType)
+		buf.Write(data)
+		buf.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(append([]byte(chunkType), data...))))
+	}
+	for _, d := range frameData {
+		var buf bytes.Buffer
+		buf.WriteString("\x89PNG\r\n\x1a\n")
+		writeChunk(&buf, "IHDR", ihdr)
+		writeChunk(&buf, "IDAT", d)
+		writeChunk(&buf, "IEND", nil)
+		img, err := png.Decode(&buf)
+		require.NoError(t, err)
+		frames = append(frames, img)
+	}
+	return
+}
+
+func Test_EncodeAPNG(t *testing.T) {
+	ass := assert.New(t)
+	frames := makeAnimationFrames()
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeAPNG(&buf, frames, &frostutil.APNGOptions{Plays: 2}))
+
+	// viewers that don't know about APNG see the first frame, with the transparent pixel's color preserved
+	first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	ass.Equal(frames[0].Image, first)
+
+	decoded, plays, delays := decodeAPNGFrames(t, buf.Bytes())
+	ass.Equal(2, plays)
+	ass.Equal([]time.Duration{100 * time.Millisecond, 250 * time.Millisecond, 33 * time.Millisecond}, delays)
+	require.Len(t, decoded, 3)
+	ass.Equal(frames[0].Image.(*image.NRGBA).Pix, decoded[0].(*image.NRGBA).Pix)
+	ass.Equal(frames[1].Image.(*image.NRGBA).Pix, decoded[1].(*image.NRGBA).Pix)
+	blue := decoded[2].(*image.NRGBA)
+	ass.Equal(image.Rect(0, 0, 4, 3), blue.Rect)
+	ass.Equal(color.NRGBA{0, 0, 255, 255}, blue.NRGBAAt(1, 1))
+	ass.Equal(color.NRGBA{}, blue.NRGBAAt(2, 1))
+	ass.Equal(color.NRGBA{}, blue.NRGBAAt(0, 2))
+
+	err = frostutil.EncodeAPNG(&buf, nil, nil)
+	var encodeErr *frostutil.ImageEncodeError
+	if ass.True(errors.As(err, &encodeErr)) {
+		ass.Equal(frostutil.ImageFormatPNG, encodeErr.Format)
+	}
+}
+
+func Test_SaveAnimations(t *testing.T) {
+	ass := assert.New(t)
+	dir := t.TempDir()
+	frames := makeAnimationFrames()
+
+	path := filepath.Join(dir, "anim.gif")
+	require.NoError(t, frostutil.SaveGIF(path, frames, nil))
+	_, format, err := frostutil.LoadImage(path)
+	require.NoError(t, err)
+	ass.Equal(frostutil.ImageFormatGIF, format)
+
+	path = filepath.Join(dir, "anim.png")
+	require.NoError(t, frostutil.SaveAPNG(path, frames, nil))
+	img, format, err := frostutil.LoadImage(path)
+	require.NoError(t, err)
+	ass.Equal(frostutil.ImageFormatPNG, format)
+	ass.Equal(frames[0].Image, img)
+}
+
+func Test_EncodeAnimationEImages(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_EncodeAnimationEImages)
+}
+
+func test_EncodeAnimationEImages(t *testing.T) {
+	ass := assert.New(t)
+	var frames []frostutil.AnimationFrame
+	for _, c := range []color.RGBA{{255, 0, 0, 255}, {0, 128, 0, 128}} {
+		eImg := ebiten.NewImage(3, 2)
+		eImg.Fill(c)
+		frames = append(frames, frostutil.AnimationFrame{Image: eImg, Delay: 50 * time.Millisecond})
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeAPNG(&buf, frames, nil))
+	decoded, _, _ := decodeAPNGFrames(t, buf.Bytes())
+	require.Len(t, decoded, 2)
+	ass.Equal(color.NRGBA{255, 0, 0, 255}, decoded[0].At(2, 1))
+	ass.Equal(color.NRGBA{0, 255, 0, 128}, decoded[1].At(2, 1))
+
+	buf.Reset()
+	require.NoError(t, frostutil.EncodeGIF(&buf, frames, nil))
+	anim, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 2)
+	ass.Equal(color.RGBA{0, 255, 0, 255}, color.RGBAModel.Convert(anim.Image[1].At(0, 0)))
+}
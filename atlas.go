@@ -0,0 +1,401 @@
+package frostutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// This file implements a texture atlas builder, which packs named images into a single *image.NRGBA using the MaxRects algorithm
+// (with the best short side fit heuristic), and records where each one went so they can be looked up by name later.
+// The packing is deterministic: the same images with the same names and options always produce the same atlas.
+
+const defaultAtlasMaxSize = 4096
+
+// AtlasOptions controls how AtlasBuilder packs images.
+type AtlasOptions struct {
+	MaxWidth, MaxHeight int  // The largest the atlas is allowed to be. If either is <= 0, it defaults to 4096.
+	Padding             int  // Transparent pixels left between sprites (outside of any extrusion).
+	Extrude             int  // How many times each sprite's edge pixels are repeated outward, so that filtering at the edges doesn't pick up its neighbors.
+	AllowRotation       bool // If true, sprites may be rotated 90 degrees clockwise if they fit better that way.
+	PowerOfTwo          bool // If true, the atlas's width and height are rounded up to powers of two.
+}
+
+// AtlasRegion is where one sprite went in an atlas.
+// X and Y are the top-left corner of the sprite in the atlas (not including extrusion), and W and H are the sprite's original, unrotated size.
+// If Rotated is true, the sprite was stored rotated 90 degrees clockwise, so it occupies H pixels horizontally and W vertically in the atlas.
+type AtlasRegion struct {
+	Name    string `json:"name"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+	W       int    `json:"w"`
+	H       int    `json:"h"`
+	Rotated bool   `json:"rotated,omitempty"`
+}
+
+// Rect returns the rectangle the sprite occupies in the atlas, which has its width and height swapped if the sprite was rotated.
+func (region AtlasRegion) Rect() image.Rectangle {
+	if region.Rotated {
+		return image.Rect(region.X, region.Y, region.X+region.H, region.Y+region.W)
+	}
+	return image.Rect(region.X, region.Y, region.X+region.W, region.Y+region.H)
+}
+
+// Atlas is a packed texture atlas. It marshals to and from JSON (without the image), so the metadata can be saved next to the atlas image
+// (with SaveImage, for instance), and read back with ReadAtlasJSON at runtime.
+type Atlas struct {
+	Width   int           `json:"width"`
+	Height  int           `json:"height"`
+	Padding int           `json:"padding"`
+	Extrude int           `json:"extrude"`
+	Regions []AtlasRegion `json:"regions"` // in the order the images were added to the builder
+	Image   *image.NRGBA  `json:"-"`       // nil if the atlas was read with ReadAtlasJSON
+	index   map[string]int
+}
+
+// Region returns the region for the sprite with the given name, and whether there was one.
+func (atlas *Atlas) Region(name string) (region AtlasRegion, ok bool) {
+	if atlas.index == nil {
+		atlas.buildIndex()
+	}
+	i, ok := atlas.index[name]
+	if ok {
+		region = atlas.Regions[i]
+	}
+	return
+}
+
+// buildIndex (re)builds the map from sprite names to indexes into Regions.
+func (atlas *Atlas) buildIndex() {
+	atlas.index = make(map[string]int, len(atlas.Regions))
+	for i, region := range atlas.Regions {
+		atlas.index[region.Name] = i
+	}
+}
+
+// SubImage returns the part of atlas.Image holding the named sprite (rotated, if region.Rotated is true), or nil if there's no such sprite or atlas.Image is nil.
+// It shares pixels with atlas.Image rather than copying them.
+func (atlas *Atlas) SubImage(name string) *image.NRGBA {
+	region, ok := atlas.Region(name)
+	if !ok || atlas.Image == nil {
+		return nil
+	}
+	return atlas.Image.SubImage(region.Rect()).(*image.NRGBA)
+}
+
+// NewEImage creates an *ebiten.Image from atlas.Image with NewEImageFromImage.
+func (atlas *Atlas) NewEImage(mipmaps bool) *ebiten.Image {
+	return NewEImageFromImage(atlas.Image, mipmaps)
+}
+
+// ESubImage returns the part of eImg holding the named sprite, or nil if there's no such sprite.
+// eImg should be the atlas image, either from NewEImage or loaded from a file saved from atlas.Image.
+func (atlas *Atlas) ESubImage(eImg *ebiten.Image, name string) *ebiten.Image {
+	region, ok := atlas.Region(name)
+	if !ok {
+		return nil
+	}
+	return eImg.SubImage(region.Rect()).(*ebiten.Image)
+}
+
+// WriteJSON writes the atlas's metadata (everything except the image) to w as JSON.
+func (atlas *Atlas) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(atlas)
+}
+
+// ReadAtlasJSON reads atlas metadata written by WriteJSON. The returned Atlas's Image is nil.
+func ReadAtlasJSON(r io.Reader) (atlas *Atlas, err error) {
+	atlas = &Atlas{}
+	if err = json.NewDecoder(r).Decode(atlas); err != nil {
+		return nil, err
+	}
+	atlas.buildIndex()
+	return
+}
+
+// atlasInput is an image waiting to be packed.
+type atlasInput struct {
+	name string
+	img  *image.NRGBA
+}
+
+// AtlasBuilder collects named images and packs them into an Atlas.
+type AtlasBuilder struct {
+	opts   AtlasOptions
+	inputs []atlasInput
+	names  map[string]bool
+}
+
+// NewAtlasBuilder creates an AtlasBuilder which will pack images according to opts.
+func NewAtlasBuilder(opts AtlasOptions) *AtlasBuilder {
+	if opts.MaxWidth <= 0 {
+		opts.MaxWidth = defaultAtlasMaxSize
+	}
+	if opts.MaxHeight <= 0 {
+		opts.MaxHeight = defaultAtlasMaxSize
+	}
+	opts.Padding = Max(opts.Padding, 0)
+	opts.Extrude = Max(opts.Extrude, 0)
+	return &AtlasBuilder{opts: opts, names: map[string]bool{}}
+}
+
+// Add queues img to be packed under name. The image is converted with NewNRGBAFromImage right away, so it's safe to modify img afterwards.
+// It returns an error if name was already added, or if img is empty.
+func (builder *AtlasBuilder) Add(name string, img image.Image) error {
+	if builder.names[name] {
+		return fmt.Errorf("atlas: an image named %q was already added", name)
+	}
+	if img.Bounds().Empty() {
+		return fmt.Errorf("atlas: image %q is empty", name)
+	}
+	builder.names[name] = true
+	builder.inputs = append(builder.inputs, atlasInput{name: name, img: NewNRGBAFromImage(img)})
+	return nil
+}
+
+// maxRectsBin is the state of the MaxRects algorithm: the free rectangles left in a bin of a fixed size.
+type maxRectsBin struct {
+	free []image.Rectangle
+}
+
+// findPosition returns the best place for a w x h rectangle using the best short side fit heuristic (the free rectangle which leaves the
+// smallest leftover on its shorter side), trying it rotated too if allowRotation is true.
+func (bin *maxRectsBin) findPosition(w, h int, allowRotation bool) (rect image.Rectangle, rotated, ok bool) {
+	bestShort, bestLong := math.MaxInt, math.MaxInt
+	try := func(w, h int, rot bool) {
+		for _, free := range bin.free {
+			if free.Dx() < w || free.Dy() < h {
+				continue
+			}
+			leftoverX, leftoverY := free.Dx()-w, free.Dy()-h
+			short, long := Min(leftoverX, leftoverY), Max(leftoverX, leftoverY)
+			if short < bestShort || (short == bestShort && long < bestLong) {
+				bestShort, bestLong = short, long
+				rect = image.Rect(free.Min.X, free.Min.Y, free.Min.X+w, free.Min.Y+h)
+				rotated = rot
+				ok = true
+			}
+		}
+	}
+	try(w, h, false)
+	if allowRotation && w != h {
+		try(h, w, true)
+	}
+	return
+}
+
+// place removes used from the free rectangles, splitting every free rectangle it overlaps into up to four smaller ones,
+// and then prunes any free rectangle which is contained in another.
+func (bin *maxRectsBin) place(used image.Rectangle) {
+	newFree := make([]image.Rectangle, 0, len(bin.free)+4)
+	for _, free := range bin.free {
+		if !free.Overlaps(used) {
+			newFree = append(newFree, free)
+			continue
+		}
+		if used.Min.X > free.Min.X {
+			newFree = append(newFree, image.Rect(free.Min.X, free.Min.Y, used.Min.X, free.Max.Y))
+		}
+		if used.Max.X < free.Max.X {
+			newFree = append(newFree, image.Rect(used.Max.X, free.Min.Y, free.Max.X, free.Max.Y))
+		}
+		if used.Min.Y > free.Min.Y {
+			newFree = append(newFree, image.Rect(free.Min.X, free.Min.Y, free.Max.X, used.Min.Y))
+		}
+		if used.Max.Y < free.Max.Y {
+			newFree = append(newFree, image.Rect(free.Min.X, used.Max.Y, free.Max.X, free.Max.Y))
+		}
+	}
+	// prune
+	bin.free = bin.free[:0]
+	for i, a := range newFree {
+		contained := false
+		for j, b := range newFree {
+			// if two rectangles are identical, keep the first
+			if i != j && a.In(b) && (a != b || j < i) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			bin.free = append(bin.free, a)
+		}
+	}
+}
+
+// nextPowerOfTwo returns the smallest power of two which is >= x.
+func nextPowerOfTwo(x int) int {
+	p := 1
+	for p < x {
+		p <<= 1
+	}
+	return p
+}
+
+// Build packs every added image into a new Atlas.
+// It starts with a bin about the size of the images' total area, and grows it until everything fits or it can't grow past MaxWidth and MaxHeight,
+// in which case it returns an error. The finished atlas is cropped to the area actually used (rounded up to powers of two if PowerOfTwo is set).
+func (builder *AtlasBuilder) Build() (atlas *Atlas, err error) {
+	opts := builder.opts
+	if len(builder.inputs) == 0 {
+		return nil, errors.New("atlas: no images were added")
+	}
+	// Each sprite takes up its own size, plus extrusion on both sides, plus padding on one side. The bin gets the same padding added
+	// on the right and bottom, so the sprites along those edges don't waste space on padding.
+	border := opts.Extrude*2 + opts.Padding
+	order := make([]int, len(builder.inputs))
+	area := 0
+	for i, input := range builder.inputs {
+		order[i] = i
+		size := input.img.Bounds().Size()
+		w, h := size.X+border, size.Y+border
+		area += w * h
+		fitsUpright := w <= opts.MaxWidth+opts.Padding && h <= opts.MaxHeight+opts.Padding
+		fitsRotated := opts.AllowRotation && h <= opts.MaxWidth+opts.Padding && w <= opts.MaxHeight+opts.Padding
+		if !fitsUpright && !fitsRotated {
+			return nil, fmt.Errorf("atlas: image %q (%vx%v) can't fit in a %vx%v atlas", input.name, size.X, size.Y, opts.MaxWidth, opts.MaxHeight)
+		}
+	}
+	// Packing the biggest sprites first gives much better results. Ties are broken by name so the output doesn't depend on anything else.
+	sort.SliceStable(order, func(a, b int) bool {
+		sa, sb := builder.inputs[order[a]].img.Bounds().Size(), builder.inputs[order[b]].img.Bounds().Size()
+		if ma, mb := Max(sa.X, sa.Y), Max(sb.X, sb.Y); ma != mb {
+			return ma > mb
+		}
+		if aa, ab := sa.X*sa.Y, sb.X*sb.Y; aa != ab {
+			return aa > ab
+		}
+		return builder.inputs[order[a]].name < builder.inputs[order[b]].name
+	})
+
+	side := nextPowerOfTwo(int(math.Ceil(math.Sqrt(float64(area)))))
+	binW, binH := Min(side, opts.MaxWidth), Min(side, opts.MaxHeight)
+	var placed []image.Rectangle
+	var rotated []bool
+	for {
+		placed, rotated = builder.pack(order, binW+opts.Padding, binH+opts.Padding)
+		if placed != nil {
+			break
+		}
+		if binW >= opts.MaxWidth && binH >= opts.MaxHeight {
+			return nil, fmt.Errorf("atlas: the images don't all fit in a %vx%v atlas", opts.MaxWidth, opts.MaxHeight)
+		}
+		// grow whichever side is smaller (or can still grow)
+		if (binW <= binH || binH >= opts.MaxHeight) && binW < opts.MaxWidth {
+			binW = Min(binW*2, opts.MaxWidth)
+		} else {
+			binH = Min(binH*2, opts.MaxHeight)
+		}
+	}
+
+	usedW, usedH := 0, 0
+	for _, rect := range placed {
+		usedW = Max(usedW, rect.Max.X-opts.Padding)
+		usedH = Max(usedH, rect.Max.Y-opts.Padding)
+	}
+	if opts.PowerOfTwo {
+		usedW = Min(nextPowerOfTwo(usedW), Max(opts.MaxWidth, usedW))
+		usedH = Min(nextPowerOfTwo(usedH), Max(opts.MaxHeight, usedH))
+	}
+	atlas = &Atlas{
+		Width:   usedW,
+		Height:  usedH,
+		Padding: opts.Padding,
+		Extrude: opts.Extrude,
+		Regions: make([]AtlasRegion, len(builder.inputs)),
+		Image:   image.NewNRGBA(image.Rect(0, 0, usedW, usedH)),
+	}
+	for i, input := range builder.inputs {
+		size := input.img.Bounds().Size()
+		region := AtlasRegion{
+			Name:    input.name,
+			X:       placed[i].Min.X + opts.Extrude,
+			Y:       placed[i].Min.Y + opts.Extrude,
+			W:       size.X,
+			H:       size.Y,
+			Rotated: rotated[i],
+		}
+		atlas.Regions[i] = region
+		src := input.img
+		if region.Rotated {
+			src = rotateNRGBAClockwise(src)
+		}
+		blitExtruded(atlas.Image, src, region.X, region.Y, opts.Extrude)
+	}
+	atlas.buildIndex()
+	return
+}
+
+// pack tries to pack every input into a binW x binH bin, in the given order.
+// It returns the rectangle each input (indexed the same as builder.inputs) occupies including its border, and whether it was rotated,
+// or nils if they don't all fit.
+func (builder *AtlasBuilder) pack(order []int, binW, binH int) (placed []image.Rectangle, rotated []bool) {
+	border := builder.opts.Extrude*2 + builder.opts.Padding
+	bin := &maxRectsBin{free: []image.Rectangle{image.Rect(0, 0, binW, binH)}}
+	placed = make([]image.Rectangle, len(builder.inputs))
+	rotated = make([]bool, len(builder.inputs))
+	for _, i := range order {
+		size := builder.inputs[i].img.Bounds().Size()
+		rect, rot, ok := bin.findPosition(size.X+border, size.Y+border, builder.opts.AllowRotation)
+		if !ok {
+			return nil, nil
+		}
+		bin.place(rect)
+		placed[i] = rect
+		rotated[i] = rot
+	}
+	return
+}
+
+// rotateNRGBAClockwise returns a copy of img rotated 90 degrees clockwise.
+func rotateNRGBAClockwise(img *image.NRGBA) *image.NRGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, h, w))
+	for y := 0; y < h; y++ {
+		iIdx := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		for x := 0; x < w; x++ {
+			// the pixel at (x, y) ends up at (h-1-y, x)
+			oIdx := out.PixOffset(h-1-y, x)
+			copy(out.Pix[oIdx:oIdx+4], img.Pix[iIdx:iIdx+4])
+			iIdx += 4
+		}
+	}
+	return out
+}
+
+// blitExtruded copies src into dst with its top-left corner at (x, y), and then repeats its edge pixels outward extrude times in every direction
+// (including the corners).
+func blitExtruded(dst, src *image.NRGBA, x, y, extrude int) {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	for row := 0; row < h; row++ {
+		iIdx := src.PixOffset(bounds.Min.X, bounds.Min.Y+row)
+		oIdx := dst.PixOffset(x, y+row)
+		copy(dst.Pix[oIdx:oIdx+w*4], src.Pix[iIdx:iIdx+w*4])
+		// extend the row to the left and right
+		for e := 1; e <= extrude; e++ {
+			copy(dst.Pix[oIdx-e*4:oIdx-e*4+4], dst.Pix[oIdx:oIdx+4])
+			right := oIdx + (w-1)*4
+			copy(dst.Pix[right+e*4:right+e*4+4], dst.Pix[right:right+4])
+		}
+	}
+	// then copy the extended top and bottom rows upward and downward
+	rowBytes := (w + extrude*2) * 4
+	top := dst.PixOffset(x-extrude, y)
+	bottom := dst.PixOffset(x-extrude, y+h-1)
+	for e := 1; e <= extrude; e++ {
+		above := top - e*dst.Stride
+		copy(dst.Pix[above:above+rowBytes], dst.Pix[top:top+rowBytes])
+		below := bottom + e*dst.Stride
+		copy(dst.Pix[below:below+rowBytes], dst.Pix[bottom:bottom+rowBytes])
+	}
+}
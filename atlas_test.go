@@ -0,0 +1,162 @@
+package frostutil_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeSprite creates a w x h *image.NRGBA where every pixel has a different color, seeded by id, so that we can tell where each pixel ended up.
+func makeSprite(id, w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: byte(x), G: byte(y), B: byte(id), A: 0xff})
+		}
+	}
+	return img
+}
+
+// buildTestAtlas packs a bunch of differently sized sprites, and returns the atlas along with the sprites by name.
+func buildTestAtlas(t *testing.T, opts frostutil.AtlasOptions) (*frostutil.Atlas, map[string]*image.NRGBA) {
+	builder := frostutil.NewAtlasBuilder(opts)
+	sprites := map[string]*image.NRGBA{}
+	sizes := [][2]int{{32, 32}, {64, 16}, {10, 50}, {7, 7}, {100, 20}, {20, 100}, {33, 17}, {1, 1}, {48, 48}, {5, 60}}
+	for i, size := range sizes {
+		name := fmt.Sprintf("sprite%v", i)
+		sprites[name] = makeSprite(i, size[0], size[1])
+		require.NoError(t, builder.Add(name, sprites[name]))
+	}
+	atlas, err := builder.Build()
+	require.NoError(t, err)
+	return atlas, sprites
+}
+
+func Test_AtlasBuild(t *testing.T) {
+	ass := assert.New(t)
+	for _, opts := range []frostutil.AtlasOptions{
+		{},
+		{Padding: 2},
+		{Padding: 1, Extrude: 2},
+		{Padding: 3, Extrude: 1, AllowRotation: true, PowerOfTwo: true},
+		{MaxWidth: 128, AllowRotation: true},
+	} {
+		atlas, sprites := buildTestAtlas(t, opts)
+		ass.Equal(len(sprites), len(atlas.Regions))
+		ass.Equal(image.Rect(0, 0, atlas.Width, atlas.Height), atlas.Image.Bounds())
+		if opts.MaxWidth > 0 {
+			ass.LessOrEqual(atlas.Width, opts.MaxWidth)
+		}
+		if opts.PowerOfTwo {
+			ass.Zero(atlas.Width&(atlas.Width-1), "width %v isn't a power of two", atlas.Width)
+			ass.Zero(atlas.Height&(atlas.Height-1), "height %v isn't a power of two", atlas.Height)
+		}
+		// The regions, grown by their extrusion, must be inside the atlas, and must be at least Padding pixels away from each other.
+		for i, a := range atlas.Regions {
+			ra := a.Rect().Inset(-opts.Extrude)
+			ass.True(ra.In(atlas.Image.Bounds()), "region %v %v is outside the atlas", a.Name, ra)
+			ra.Max = ra.Max.Add(image.Pt(opts.Padding, opts.Padding))
+			for j, b := range atlas.Regions {
+				rb := b.Rect().Inset(-opts.Extrude)
+				ass.True(i == j || !ra.Overlaps(rb), "regions %v and %v are too close with opts %+v", a.Name, b.Name, opts)
+			}
+		}
+		for name, sprite := range sprites {
+			region, ok := atlas.Region(name)
+			require.True(t, ok)
+			ass.Equal(sprite.Bounds().Size(), image.Pt(region.W, region.H))
+			sub := atlas.SubImage(name)
+			for y := 0; y < region.H; y++ {
+				for x := 0; x < region.W; x++ {
+					ax, ay := region.X+x, region.Y+y
+					if region.Rotated {
+						ax, ay = region.X+region.H-1-y, region.Y+x
+					}
+					if sprite.NRGBAAt(x, y) != sub.NRGBAAt(ax, ay) {
+						ass.Failf("atlas pixel mismatch", "%v pixel (%v, %v) with opts %+v", name, x, y, opts)
+						return
+					}
+				}
+			}
+			if opts.Extrude > 0 && !region.Rotated {
+				// the corners and edges are repeated outward
+				ass.Equal(sprite.NRGBAAt(0, 0), atlas.Image.NRGBAAt(region.X-opts.Extrude, region.Y-opts.Extrude))
+				ass.Equal(sprite.NRGBAAt(region.W-1, region.H-1), atlas.Image.NRGBAAt(region.X+region.W-1+opts.Extrude, region.Y+region.H-1+opts.Extrude))
+				ass.Equal(sprite.NRGBAAt(0, region.H/2), atlas.Image.NRGBAAt(region.X-opts.Extrude, region.Y+region.H/2))
+			}
+		}
+	}
+}
+
+func Test_AtlasJSON(t *testing.T) {
+	ass := assert.New(t)
+	atlas, sprites := buildTestAtlas(t, frostutil.AtlasOptions{Padding: 1, AllowRotation: true})
+	var buf bytes.Buffer
+	require.NoError(t, atlas.WriteJSON(&buf))
+	loaded, err := frostutil.ReadAtlasJSON(&buf)
+	require.NoError(t, err)
+	ass.Nil(loaded.Image)
+	ass.Equal(atlas.Width, loaded.Width)
+	ass.Equal(atlas.Height, loaded.Height)
+	ass.Equal(atlas.Regions, loaded.Regions)
+	for name := range sprites {
+		region, ok := loaded.Region(name)
+		ass.True(ok)
+		expected, _ := atlas.Region(name)
+		ass.Equal(expected, region)
+	}
+	_, ok := loaded.Region("missing")
+	ass.False(ok)
+	ass.Nil(loaded.SubImage("sprite0"))
+}
+
+func Test_AtlasErrors(t *testing.T) {
+	ass := assert.New(t)
+	builder := frostutil.NewAtlasBuilder(frostutil.AtlasOptions{MaxWidth: 64, MaxHeight: 64})
+	_, err := builder.Build()
+	ass.Error(err)
+	ass.NoError(builder.Add("a", makeSprite(0, 40, 40)))
+	ass.Error(builder.Add("a", makeSprite(0, 10, 10)))
+	ass.Error(builder.Add("empty", image.NewNRGBA(image.Rect(0, 0, 0, 5))))
+	ass.NoError(builder.Add("b", makeSprite(1, 40, 40)))
+	_, err = builder.Build()
+	ass.Error(err)
+
+	builder = frostutil.NewAtlasBuilder(frostutil.AtlasOptions{MaxWidth: 64, MaxHeight: 64})
+	ass.NoError(builder.Add("wide", makeSprite(0, 100, 10)))
+	_, err = builder.Build()
+	ass.Error(err)
+
+	// it does fit if it can be rotated
+	builder = frostutil.NewAtlasBuilder(frostutil.AtlasOptions{MaxWidth: 16, MaxHeight: 128, AllowRotation: true})
+	ass.NoError(builder.Add("wide", makeSprite(0, 100, 10)))
+	atlas, err := builder.Build()
+	require.NoError(t, err)
+	region, _ := atlas.Region("wide")
+	ass.True(region.Rotated)
+	ass.Equal(image.Rect(0, 0, 10, 100), region.Rect())
+}
+
+func Test_AtlasESubImage(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_AtlasESubImage)
+}
+
+func test_AtlasESubImage(t *testing.T) {
+	ass := assert.New(t)
+	atlas, sprites := buildTestAtlas(t, frostutil.AtlasOptions{Padding: 1})
+	eImg := atlas.NewEImage(false)
+	for name, sprite := range sprites {
+		sub := atlas.ESubImage(eImg, name)
+		require.NotNil(t, sub)
+		region, _ := atlas.Region(name)
+		ass.Equal(region.Rect(), sub.Bounds())
+		ass.Equal(color.RGBA{sprite.Pix[0], sprite.Pix[1], sprite.Pix[2], 0xff}, sub.At(region.X, region.Y))
+	}
+	ass.Nil(atlas.ESubImage(eImg, "missing"))
+}
@@ -0,0 +1,167 @@
+package frostutil
+
+import (
+	"image"
+	"math"
+	"math/bits"
+)
+
+// CollisionMask is a bitset with one bit per pixel of an image, which is set if that pixel is solid, for pixel-perfect collision tests.
+// Each row is stored as 64-bit words (the leftmost pixel in the lowest bit), so that overlap tests can check 64 pixels at a time.
+// Masks are in their own coordinate space, with the top-left pixel at (0, 0). The query methods take the positions of other things relative to that.
+type CollisionMask struct {
+	width, height int
+	stride        int // words per row
+	bits          []uint64
+}
+
+// NewCollisionMask creates a CollisionMask from img's alpha channel, where pixels with alpha greater than threshold are solid.
+// *ebiten.Images are read with a single ReadPixels call, and *image.RGBA, *image.NRGBA, *image.Alpha, and *image.Gray images straight from their pixel buffers.
+// Since reading pixels back from the GPU is slow, masks should be created once (when loading sprites) rather than every frame.
+func NewCollisionMask(img image.Image, threshold byte) *CollisionMask {
+	alpha, width, height := alphaValues(img)
+	m := newCollisionMask(width, height)
+	for y := 0; y < height; y++ {
+		row := m.bits[y*m.stride : (y+1)*m.stride]
+		for x, a := range alpha[y*width : (y+1)*width] {
+			if a > threshold {
+				row[x>>6] |= 1 << (x & 63)
+			}
+		}
+	}
+	return m
+}
+
+// newCollisionMask creates an empty width x height mask.
+func newCollisionMask(width, height int) *CollisionMask {
+	width, height = Max(width, 0), Max(height, 0)
+	stride := (width + 63) >> 6
+	return &CollisionMask{width: width, height: height, stride: stride, bits: make([]uint64, stride*height)}
+}
+
+// Bounds returns the mask's bounds, which always have their top-left corner at (0, 0).
+func (m *CollisionMask) Bounds() image.Rectangle {
+	return image.Rect(0, 0, m.width, m.height)
+}
+
+// At returns whether the pixel at (x, y) is solid. Pixels outside the mask aren't.
+func (m *CollisionMask) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return false
+	}
+	return m.bits[y*m.stride+x>>6]&(1<<(x&63)) != 0
+}
+
+// Set makes the pixel at (x, y) solid or not. Pixels outside the mask are ignored.
+func (m *CollisionMask) Set(x, y int, solid bool) {
+	if x < 0 || y < 0 || x >= m.width || y >= m.height {
+		return
+	}
+	if solid {
+		m.bits[y*m.stride+x>>6] |= 1 << (x & 63)
+	} else {
+		m.bits[y*m.stride+x>>6] &^= 1 << (x & 63)
+	}
+}
+
+// HitsPoint returns whether the point (x, y), relative to the mask's top-left corner, is on a solid pixel. It's the same as At.
+func (m *CollisionMask) HitsPoint(x, y int) bool {
+	return m.At(x, y)
+}
+
+// Count returns how many solid pixels there are.
+func (m *CollisionMask) Count() (count int) {
+	for _, word := range m.bits {
+		count += bits.OnesCount64(word)
+	}
+	return
+}
+
+// rowBits returns the 64 bits of a row starting at column start, with columns outside the row as 0.
+func rowBits(row []uint64, start int) uint64 {
+	if start < 0 {
+		if start <= -64 || len(row) == 0 {
+			return 0
+		}
+		return row[0] << -start
+	}
+	w, shift := start>>6, start&63
+	if w >= len(row) {
+		return 0
+	}
+	out := row[w] >> shift
+	if shift != 0 && w+1 < len(row) {
+		out |= row[w+1] << (64 - shift)
+	}
+	return out
+}
+
+// Overlaps returns whether any solid pixel of m overlaps a solid pixel of other, when other's top-left corner is at (dx, dy) relative to m's.
+// It only looks at the rows and 64-pixel words where the masks overlap.
+func (m *CollisionMask) Overlaps(other *CollisionMask, dx, dy int) bool {
+	overlap := m.Bounds().Intersect(other.Bounds().Add(image.Pt(dx, dy)))
+	if overlap.Empty() {
+		return false
+	}
+	firstWord, lastWord := overlap.Min.X>>6, (overlap.Max.X-1)>>6
+	for y := overlap.Min.Y; y < overlap.Max.Y; y++ {
+		row := m.bits[y*m.stride : (y+1)*m.stride]
+		oRow := other.bits[(y-dy)*other.stride : (y-dy+1)*other.stride]
+		for w := firstWord; w <= lastWord; w++ {
+			if row[w] != 0 && row[w]&rowBits(oRow, w<<6-dx) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OverlapsRect returns whether any solid pixel of m is inside rect, which is relative to m's top-left corner.
+func (m *CollisionMask) OverlapsRect(rect image.Rectangle) bool {
+	rect = rect.Intersect(m.Bounds())
+	if rect.Empty() {
+		return false
+	}
+	firstWord, lastWord := rect.Min.X>>6, (rect.Max.X-1)>>6
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		row := m.bits[y*m.stride : (y+1)*m.stride]
+		for w := firstWord; w <= lastWord; w++ {
+			mask := ^uint64(0)
+			if w == firstWord {
+				mask <<= rect.Min.X & 63
+			}
+			if w == lastWord && rect.Max.X&63 != 0 {
+				mask &= (1 << (rect.Max.X & 63)) - 1
+			}
+			if row[w]&mask != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Scaled returns a new mask scaled by (scaleX, scaleY) with nearest neighbor sampling. A negative scale flips the mask on that axis,
+// like scaling with ebiten.GeoM, so Scaled(-1, 1) mirrors it left to right. The new mask's size is the old size times the scale's absolute value,
+// rounded up, and like every mask its top-left corner is at (0, 0). If either scale is 0, the mask is empty.
+// Like NewCollisionMask, this is meant to be done ahead of time, such as once for each direction a sprite can face.
+func (m *CollisionMask) Scaled(scaleX, scaleY float64) *CollisionMask {
+	absX, absY := math.Abs(scaleX), math.Abs(scaleY)
+	out := newCollisionMask(int(math.Ceil(float64(m.width)*absX)), int(math.Ceil(float64(m.height)*absY)))
+	for y := 0; y < out.height; y++ {
+		sy := Min(int((float64(y)+0.5)/absY), m.height-1)
+		if scaleY < 0 {
+			sy = m.height - 1 - sy
+		}
+		for x := 0; x < out.width; x++ {
+			sx := Min(int((float64(x)+0.5)/absX), m.width-1)
+			if scaleX < 0 {
+				sx = m.width - 1 - sx
+			}
+			if m.At(sx, sy) {
+				out.bits[y*out.stride+x>>6] |= 1 << (x & 63)
+			}
+		}
+	}
+	return out
+}
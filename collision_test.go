@@ -0,0 +1,133 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeRandomMaskImage makes a w x h *image.NRGBA where roughly one pixel in density is solid.
+func makeRandomMaskImage(rng *rand.Rand, w, h, density int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if rng.Intn(density) == 0 {
+				img.SetNRGBA(x, y, color.NRGBA{A: byte(128 + rng.Intn(128))})
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{A: byte(rng.Intn(10))})
+			}
+		}
+	}
+	return img
+}
+
+func Test_CollisionMask(t *testing.T) {
+	ass := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+	img := makeRandomMaskImage(rng, 70, 9, 3)
+	mask := frostutil.NewCollisionMask(img, 100)
+	ass.Equal(image.Rect(0, 0, 70, 9), mask.Bounds())
+	count := 0
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 70; x++ {
+			solid := img.NRGBAAt(x, y).A > 100
+			ass.Equal(solid, mask.At(x, y))
+			ass.Equal(solid, mask.HitsPoint(x, y))
+			if solid {
+				count++
+			}
+		}
+	}
+	ass.Equal(count, mask.Count())
+	ass.False(mask.At(-1, 0))
+	ass.False(mask.At(70, 0))
+
+	// the threshold counts
+	ass.Greater(frostutil.NewCollisionMask(img, 0).Count(), count)
+	ass.Zero(frostutil.NewCollisionMask(img, 255).Count())
+}
+
+func Test_CollisionMaskOverlaps(t *testing.T) {
+	ass := assert.New(t)
+	rng := rand.New(rand.NewSource(2))
+	a := frostutil.NewCollisionMask(makeRandomMaskImage(rng, 150, 12, 40), 100)
+	b := frostutil.NewCollisionMask(makeRandomMaskImage(rng, 67, 10, 15), 100)
+	for dy := -11; dy <= 13; dy += 2 {
+		for dx := -70; dx <= 152; dx += 3 {
+			// the slow way
+			expected := false
+			for y := 0; y < 10 && !expected; y++ {
+				for x := 0; x < 67; x++ {
+					if b.At(x, y) && a.At(x+dx, y+dy) {
+						expected = true
+						break
+					}
+				}
+			}
+			if !ass.Equal(expected, a.Overlaps(b, dx, dy), "offset (%v, %v)", dx, dy) {
+				return
+			}
+			ass.Equal(expected, b.Overlaps(a, -dx, -dy), "reversed offset (%v, %v)", dx, dy)
+		}
+	}
+
+	for _, rect := range []image.Rectangle{image.Rect(0, 0, 150, 12), image.Rect(63, 2, 65, 5), image.Rect(100, 0, 140, 1), image.Rect(-5, -5, 3, 3), image.Rect(149, 11, 200, 20)} {
+		expected := false
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				expected = expected || a.At(x, y)
+			}
+		}
+		ass.Equal(expected, a.OverlapsRect(rect), "rect %v", rect)
+	}
+	ass.False(a.OverlapsRect(image.Rect(200, 0, 210, 5)))
+}
+
+func Test_CollisionMaskScaled(t *testing.T) {
+	ass := assert.New(t)
+	// a lopsided shape, so that flipping it changes it
+	mask := frostutil.NewCollisionMask(&image.Alpha{Pix: []byte{
+		255, 255, 0, 0, 0,
+		0, 255, 0, 255, 0,
+		0, 0, 0, 255, 255,
+	}, Stride: 5, Rect: image.Rect(0, 0, 5, 3)}, 100)
+	ass.Equal(6, mask.Count())
+
+	doubled := mask.Scaled(2, 2)
+	ass.Equal(image.Rect(0, 0, 10, 6), doubled.Bounds())
+	flipped := mask.Scaled(-1, 1)
+	upsideDown := mask.Scaled(1, -1)
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 10; x++ {
+			ass.Equal(mask.At(x/2, y/2), doubled.At(x, y))
+		}
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			ass.Equal(mask.At(x, y), flipped.At(4-x, y))
+			ass.Equal(mask.At(x, y), upsideDown.At(x, 2-y))
+		}
+	}
+	ass.Equal(image.Rect(0, 0, 3, 2), mask.Scaled(-0.5, 0.5).Bounds())
+	ass.Zero(mask.Scaled(0, 1).Count())
+
+	mask.Set(0, 0, true)
+	ass.True(mask.At(0, 0))
+	mask.Set(0, 0, false)
+	ass.False(mask.At(0, 0))
+}
+
+func Test_CollisionMaskEImage(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_CollisionMaskEImage)
+}
+
+func test_CollisionMaskEImage(t *testing.T) {
+	ass := assert.New(t)
+	img := makeRandomMaskImage(rand.New(rand.NewSource(4)), 20, 20, 4)
+	eImg := frostutil.NewEImageFromImage(img, false)
+	ass.Equal(frostutil.NewCollisionMask(img, 100), frostutil.NewCollisionMask(eImg, 100))
+}
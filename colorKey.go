@@ -0,0 +1,98 @@
+package frostutil
+
+import (
+	"image"
+	"image/color"
+)
+
+// MagentaColorKey is the color that most old sprite packs use for transparency, #FF00FF.
+var MagentaColorKey = color.NRGBA{R: 0xff, B: 0xff, A: 0xff}
+
+// ColorKeyOptions controls ColorKey.
+type ColorKeyOptions struct {
+	Key        color.Color // The color to make transparent. If it's nil, MagentaColorKey is used. Its alpha component is ignored.
+	UseTopLeft bool        // If true, the color of the image's top-left pixel is used as the key instead of Key.
+	// Pixels whose red, green, and blue components are each within Tolerance of the key's are made transparent. 0 means only exact matches.
+	Tolerance byte
+	// If Despill is true, the pixels bordering the transparent ones (including diagonally) have the key color unmixed from them,
+	// which removes the colored fringe left around sprites that were antialiased against the key. Each fringe pixel is made as transparent
+	// as it can be while still producing its original color when drawn over the key color, and its color is corrected to match.
+	Despill bool
+}
+
+// ColorKey returns a copy of img (made with NewNRGBAFromImage, so its top-left corner is at (0, 0)) with every pixel that matches the color key
+// made fully transparent, as described in ColorKeyOptions. Matching pixels become transparent black, so that the key color doesn't get picked up
+// by filtering (AlphaBleed can fill them with their neighbors' colors afterwards, if you want). The result is ready for NewEImageFromImage.
+func ColorKey(img image.Image, opts ColorKeyOptions) *image.NRGBA {
+	ret := NewNRGBAFromImage(img)
+	bounds := ret.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return ret
+	}
+	var kr, kg, kb byte
+	if opts.UseTopLeft {
+		kr, kg, kb = ret.Pix[0], ret.Pix[1], ret.Pix[2]
+	} else if opts.Key != nil {
+		kr, kg, kb, _ = ToNRGBA(opts.Key)
+	} else {
+		kr, kg, kb = MagentaColorKey.R, MagentaColorKey.G, MagentaColorKey.B
+	}
+	within := func(c, k byte) bool {
+		if c > k {
+			return c-k <= opts.Tolerance
+		}
+		return k-c <= opts.Tolerance
+	}
+
+	keyed := make([]bool, width*height)
+	for y := 0; y < height; y++ {
+		idx := y * ret.Stride
+		for x := 0; x < width; x++ {
+			if within(ret.Pix[idx], kr) && within(ret.Pix[idx+1], kg) && within(ret.Pix[idx+2], kb) {
+				keyed[y*width+x] = true
+				ret.Pix[idx], ret.Pix[idx+1], ret.Pix[idx+2], ret.Pix[idx+3] = 0, 0, 0, 0
+			}
+			idx += 4
+		}
+	}
+
+	if opts.Despill {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				i := y*width + x
+				if keyed[i] || !hasFilledNeighbor(keyed, x, y, width, height) {
+					continue
+				}
+				idx := y*ret.Stride + x*4
+				r, g, b, a := despillPixel(ret.Pix[idx], ret.Pix[idx+1], ret.Pix[idx+2], kr, kg, kb)
+				ret.Pix[idx], ret.Pix[idx+1], ret.Pix[idx+2] = r, g, b
+				ret.Pix[idx+3] = byte((uint32(a)*uint32(ret.Pix[idx+3]) + 127) / 255)
+			}
+		}
+	}
+	return ret
+}
+
+// despillPixel finds the most transparent color which gives (r, g, b) when drawn over the key color (kr, kg, kb), and returns it as straight (non-premultiplied) color
+// and alpha components. This is the same unmixing that GIMP's "Color to Alpha" does: each component's alpha is how far the pixel is from the key, relative to how far
+// it could go in that direction (to 0 or 255), and the pixel's alpha is the largest of those.
+func despillPixel(r, g, b, kr, kg, kb byte) (or, og, ob, oa byte) {
+	componentAlpha := func(c, k byte) float32 {
+		if c > k {
+			return float32(c-k) / float32(0xff-k)
+		} else if c < k {
+			return float32(k-c) / float32(k)
+		}
+		return 0
+	}
+	alpha := Max(componentAlpha(r, kr), Max(componentAlpha(g, kg), componentAlpha(b, kb)))
+	if alpha <= 0 {
+		return 0, 0, 0, 0
+	}
+	unmix := func(c, k byte) byte {
+		// c = alpha * out + (1 - alpha) * k
+		return byte(clampUnit((float32(c)-(1-alpha)*float32(k))/alpha/0xff)*0xff + 0.5)
+	}
+	return unmix(r, kr), unmix(g, kg), unmix(b, kb), byte(alpha*0xff + 0.5)
+}
@@ -0,0 +1,53 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ColorKey(t *testing.T) {
+	ass := assert.New(t)
+	// magenta, with a black pixel at (2, 1), a magenta-tinted black fringe pixel at (1, 1), a nearly magenta pixel at (3, 1), and a white pixel at (4, 2)
+	img := image.NewRGBA(image.Rect(0, 0, 5, 3))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 255, 0, 255, 255
+	}
+	img.SetRGBA(2, 1, color.RGBA{A: 255})
+	img.SetRGBA(1, 1, color.RGBA{R: 200, B: 200, A: 255})
+	img.SetRGBA(3, 1, color.RGBA{R: 250, G: 3, B: 255, A: 255})
+	img.SetRGBA(4, 2, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	keyed := frostutil.ColorKey(img, frostutil.ColorKeyOptions{})
+	ass.Equal(img.Bounds(), keyed.Bounds())
+	ass.Equal(color.NRGBA{}, keyed.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{A: 255}, keyed.NRGBAAt(2, 1))
+	ass.Equal(color.NRGBA{R: 200, B: 200, A: 255}, keyed.NRGBAAt(1, 1))
+	ass.Equal(color.NRGBA{R: 250, G: 3, B: 255, A: 255}, keyed.NRGBAAt(3, 1))
+
+	// within the tolerance
+	keyed = frostutil.ColorKey(img, frostutil.ColorKeyOptions{Tolerance: 5})
+	ass.Equal(color.NRGBA{}, keyed.NRGBAAt(3, 1))
+	ass.Equal(color.NRGBA{R: 200, B: 200, A: 255}, keyed.NRGBAAt(1, 1))
+
+	// despilling turns the fringe into partially transparent black, and leaves opaque colors alone
+	keyed = frostutil.ColorKey(img, frostutil.ColorKeyOptions{Tolerance: 5, Despill: true})
+	ass.Equal(color.NRGBA{A: 55}, keyed.NRGBAAt(1, 1))
+	ass.Equal(color.NRGBA{A: 255}, keyed.NRGBAAt(2, 1))
+	ass.Equal(color.NRGBA{R: 255, G: 255, B: 255, A: 255}, keyed.NRGBAAt(4, 2))
+
+	// using the top-left pixel's color, and a different key
+	img.SetRGBA(0, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	keyed = frostutil.ColorKey(img, frostutil.ColorKeyOptions{UseTopLeft: true})
+	ass.Equal(color.NRGBA{}, keyed.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{}, keyed.NRGBAAt(4, 2))
+	ass.Equal(color.NRGBA{R: 255, B: 255, A: 255}, keyed.NRGBAAt(1, 0))
+	keyed = frostutil.ColorKey(img, frostutil.ColorKeyOptions{Key: color.Black})
+	ass.Equal(color.NRGBA{}, keyed.NRGBAAt(2, 1))
+	ass.Equal(color.NRGBA{R: 255, B: 255, A: 255}, keyed.NRGBAAt(1, 0))
+
+	ass.Equal(image.Rectangle{}, frostutil.ColorKey(image.NewRGBA(image.Rectangle{}), frostutil.ColorKeyOptions{UseTopLeft: true}).Bounds())
+}
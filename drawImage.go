@@ -0,0 +1,143 @@
+package frostutil
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// DrawImageOptions holds the options for DrawImage, which are the same as the ones in ebiten.DrawImageOptions,
+// except for the GeoM, which DrawImage takes separately, and the deprecated ColorM and CompositeMode, which aren't supported.
+type DrawImageOptions struct {
+	ColorScale ebiten.ColorScale // Scales the source's alpha-premultiplied colors. The default (zero) value is identity.
+	Blend      ebiten.Blend      // How the source and destination colors are combined. The default (zero) value is source-over.
+	Filter     ebiten.Filter     // ebiten.FilterNearest (the default) or ebiten.FilterLinear.
+}
+
+// DrawImage is a CPU reference implementation of ebiten's (*Image).DrawImage, so that rendering logic can be checked in plain unit tests,
+// without a GPU, and compared with what Ebitengine draws (within a small tolerance, since GPUs round differently) with MatchesImage-style tests.
+// opts may be nil, for the defaults.
+//
+// Like Ebitengine, it works in alpha-premultiplied colors. geoM maps src's top-left corner (src.Bounds().Min) to (0, 0) and transforms it into
+// dst's coordinate space (so drawing into a sub-image uses the same coordinates as drawing into the whole image, and is clipped to the sub-image).
+// Each dst pixel whose center falls inside the transformed src rectangle is drawn: its center is mapped back into src, and with ebiten.FilterNearest
+// the pixel it lands in is used, while with ebiten.FilterLinear the four pixels nearest to it are interpolated, with transparent pixels past src's edges.
+// The color is then multiplied by the ColorScale, clamped to [0, 1], and combined with the dst pixel with the Blend's factors and operations.
+func DrawImage(dst, src *image.RGBA, geoM ebiten.GeoM, opts *DrawImageOptions) {
+	if opts == nil {
+		opts = &DrawImageOptions{}
+	}
+	if !geoM.IsInvertible() {
+		return
+	}
+	srcBounds := src.Bounds()
+	srcW, srcH := float64(srcBounds.Dx()), float64(srcBounds.Dy())
+	// Only the dst pixels inside the transformed src rectangle's bounding box need to be looked at.
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, corner := range [4][2]float64{{0, 0}, {srcW, 0}, {0, srcH}, {srcW, srcH}} {
+		x, y := geoM.Apply(corner[0], corner[1])
+		minX, minY, maxX, maxY = math.Min(minX, x), math.Min(minY, y), math.Max(maxX, x), math.Max(maxY, y)
+	}
+	area := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(dst.Bounds())
+	if area.Empty() {
+		return
+	}
+	inverse := geoM
+	inverse.Invert()
+	scale := [4]float32{opts.ColorScale.R(), opts.ColorScale.G(), opts.ColorScale.B(), opts.ColorScale.A()}
+	texel := func(x, y int) (c [4]float32) {
+		if x < 0 || y < 0 || x >= srcBounds.Dx() || y >= srcBounds.Dy() {
+			return
+		}
+		idx := src.PixOffset(srcBounds.Min.X+x, srcBounds.Min.Y+y)
+		for i := range c {
+			c[i] = float32(src.Pix[idx+i]) / 0xff
+		}
+		return
+	}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			u, v := inverse.Apply(float64(x)+0.5, float64(y)+0.5)
+			if u < 0 || v < 0 || u >= srcW || v >= srcH {
+				continue
+			}
+			var s [4]float32
+			if opts.Filter == ebiten.FilterLinear {
+				fx, fy := math.Floor(u-0.5), math.Floor(v-0.5)
+				rx, ry := float32(u-0.5-fx), float32(v-0.5-fy)
+				tx, ty := int(fx), int(fy)
+				c0, c1, c2, c3 := texel(tx, ty), texel(tx+1, ty), texel(tx, ty+1), texel(tx+1, ty+1)
+				for i := range s {
+					s[i] = (c0[i]*(1-rx)+c1[i]*rx)*(1-ry) + (c2[i]*(1-rx)+c3[i]*rx)*ry
+				}
+			} else {
+				s = texel(int(math.Floor(u)), int(math.Floor(v)))
+			}
+			for i := range s {
+				s[i] = clampUnit(s[i] * scale[i])
+			}
+			idx := dst.PixOffset(x, y)
+			var d [4]float32
+			for i := range d {
+				d[i] = float32(dst.Pix[idx+i]) / 0xff
+			}
+			for i := range s {
+				dst.Pix[idx+i] = byte(clampUnit(blendComponent(opts.Blend, s, d, i))*0xff + 0.5)
+			}
+		}
+	}
+}
+
+// blendComponent returns component i (0-3 for red, green, blue, and alpha) of the result of blending the alpha-premultiplied source color s
+// with the destination color d.
+func blendComponent(blend ebiten.Blend, s, d [4]float32, i int) float32 {
+	srcFactor, dstFactor, op := blend.BlendFactorSourceRGB, blend.BlendFactorDestinationRGB, blend.BlendOperationRGB
+	if i == 3 {
+		srcFactor, dstFactor, op = blend.BlendFactorSourceAlpha, blend.BlendFactorDestinationAlpha, blend.BlendOperationAlpha
+	}
+	a, b := s[i]*blendFactor(srcFactor, true, s, d, i), d[i]*blendFactor(dstFactor, false, s, d, i)
+	switch op {
+	case ebiten.BlendOperationSubtract:
+		return a - b
+	case ebiten.BlendOperationReverseSubtract:
+		return b - a
+	case ebiten.BlendOperationMin:
+		return Min(s[i], d[i])
+	case ebiten.BlendOperationMax:
+		return Max(s[i], d[i])
+	default:
+		return a + b
+	}
+}
+
+// blendFactor returns the value of factor f for component i. BlendFactorDefault is one for the source and one minus the source alpha for the
+// destination, so a zero Blend is source-over, like in Ebitengine.
+func blendFactor(f ebiten.BlendFactor, source bool, s, d [4]float32, i int) float32 {
+	switch f {
+	case ebiten.BlendFactorZero:
+		return 0
+	case ebiten.BlendFactorOne:
+		return 1
+	case ebiten.BlendFactorSourceColor:
+		return s[i]
+	case ebiten.BlendFactorOneMinusSourceColor:
+		return 1 - s[i]
+	case ebiten.BlendFactorSourceAlpha:
+		return s[3]
+	case ebiten.BlendFactorOneMinusSourceAlpha:
+		return 1 - s[3]
+	case ebiten.BlendFactorDestinationColor:
+		return d[i]
+	case ebiten.BlendFactorOneMinusDestinationColor:
+		return 1 - d[i]
+	case ebiten.BlendFactorDestinationAlpha:
+		return d[3]
+	case ebiten.BlendFactorOneMinusDestinationAlpha:
+		return 1 - d[3]
+	}
+	if source {
+		return 1
+	}
+	return 1 - s[3]
+}
@@ -0,0 +1,210 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeDrawSprite makes a 4x3 *image.RGBA with a different opaque color in each pixel, and a translucent one at (3, 2).
+func makeDrawSprite() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetRGBA(x, y, color.RGBA{byte(x * 60), byte(y * 100), 200, 255})
+		}
+	}
+	img.SetRGBA(3, 2, color.RGBA{0, 50, 100, 128})
+	return img
+}
+
+func Test_DrawImage(t *testing.T) {
+	ass := assert.New(t)
+	src := makeDrawSprite()
+
+	// with the identity GeoM and no options, drawing onto a transparent image copies the source
+	dst := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	frostutil.DrawImage(dst, src, ebiten.GeoM{}, nil)
+	ass.Equal(src.Pix, dst.Pix)
+
+	// translating moves it, and only the pixels it covers are touched
+	dst = image.NewRGBA(image.Rect(0, 0, 8, 8))
+	var geoM ebiten.GeoM
+	geoM.Translate(2, 1)
+	frostutil.DrawImage(dst, src, geoM, nil)
+	ass.Equal(src.RGBAAt(0, 0), dst.RGBAAt(2, 1))
+	ass.Equal(src.RGBAAt(3, 1), dst.RGBAAt(5, 2))
+	ass.Equal(color.RGBA{}, dst.RGBAAt(1, 1))
+	ass.Equal(color.RGBA{}, dst.RGBAAt(6, 1))
+
+	// scaling up with the nearest filter makes each pixel a block
+	dst = image.NewRGBA(image.Rect(0, 0, 8, 6))
+	geoM.Reset()
+	geoM.Scale(2, 2)
+	frostutil.DrawImage(dst, src, geoM, nil)
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			ass.Equal(src.RGBAAt(x/2, y/2), dst.RGBAAt(x, y), "(%v, %v)", x, y)
+		}
+	}
+
+	// flipping and rotating match the CPU transforms
+	dst = image.NewRGBA(image.Rect(0, 0, 4, 3))
+	geoM.Reset()
+	geoM.Scale(-1, 1)
+	geoM.Translate(4, 0)
+	frostutil.DrawImage(dst, src, geoM, &frostutil.DrawImageOptions{Blend: ebiten.BlendCopy})
+	ass.Equal(frostutil.FlipHorizontal(src).Pix, dst.Pix)
+	dst = image.NewRGBA(image.Rect(0, 0, 3, 4))
+	geoM.Reset()
+	geoM.Rotate(math.Pi / 2)
+	geoM.Translate(3, 0)
+	frostutil.DrawImage(dst, src, geoM, &frostutil.DrawImageOptions{Blend: ebiten.BlendCopy})
+	ass.Equal(frostutil.Rotate90(src).Pix, dst.Pix)
+
+	// sub-images are drawn from their top-left corner, and drawing into a sub-image uses the whole image's coordinates, clipped to the sub-image
+	dst = image.NewRGBA(image.Rect(0, 0, 4, 3))
+	frostutil.DrawImage(dst.SubImage(image.Rect(1, 0, 3, 3)).(*image.RGBA), src.SubImage(image.Rect(1, 1, 4, 3)).(*image.RGBA), ebiten.GeoM{}, nil)
+	ass.Equal(color.RGBA{}, dst.RGBAAt(0, 0))
+	ass.Equal(src.RGBAAt(2, 1), dst.RGBAAt(1, 0))
+	ass.Equal(src.RGBAAt(3, 2), dst.RGBAAt(2, 1))
+	ass.Equal(color.RGBA{}, dst.RGBAAt(2, 2))
+	ass.Equal(color.RGBA{}, dst.RGBAAt(3, 0))
+
+	// a GeoM that squashes everything to a line draws nothing
+	geoM.Reset()
+	geoM.Scale(0, 1)
+	dst = image.NewRGBA(image.Rect(0, 0, 4, 3))
+	frostutil.DrawImage(dst, src, geoM, nil)
+	ass.Equal(make([]byte, len(dst.Pix)), dst.Pix)
+}
+
+func Test_DrawImageLinear(t *testing.T) {
+	ass := assert.New(t)
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+	src.SetRGBA(1, 0, color.RGBA{255, 255, 255, 255})
+
+	// scaling up 2x horizontally puts the destination pixels' centers at a quarter and three quarters of the way through each source pixel,
+	// and past the source's edges, the missing pixels are transparent
+	dst := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	var geoM ebiten.GeoM
+	geoM.Scale(2, 1)
+	frostutil.DrawImage(dst, src, geoM, &frostutil.DrawImageOptions{Filter: ebiten.FilterLinear})
+	ass.Equal([]color.RGBA{{0, 0, 0, 191}, {64, 64, 64, 255}, {191, 191, 191, 255}, {191, 191, 191, 191}},
+		[]color.RGBA{dst.RGBAAt(0, 0), dst.RGBAAt(1, 0), dst.RGBAAt(2, 0), dst.RGBAAt(3, 0)})
+
+	// at 1x with whole-pixel translations, linear filtering changes nothing
+	dst = image.NewRGBA(image.Rect(0, 0, 2, 1))
+	frostutil.DrawImage(dst, src, ebiten.GeoM{}, &frostutil.DrawImageOptions{Filter: ebiten.FilterLinear})
+	ass.Equal(src.Pix, dst.Pix)
+}
+
+func Test_DrawImageColorScaleAndBlend(t *testing.T) {
+	ass := assert.New(t)
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	src.SetRGBA(0, 0, color.RGBA{200, 100, 0, 200})
+	draw := func(dstColor color.RGBA, opts *frostutil.DrawImageOptions) color.RGBA {
+		dst := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		dst.SetRGBA(0, 0, dstColor)
+		frostutil.DrawImage(dst, src, ebiten.GeoM{}, opts)
+		return dst.RGBAAt(0, 0)
+	}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	// source-over is the default: c_src + c_dst * (1 - a_src)
+	ass.Equal(color.RGBA{200, 100, 55, 255}, draw(blue, nil))
+	ass.Equal(color.RGBA{200, 100, 55, 255}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.BlendSourceOver}))
+
+	// the color scale multiplies the premultiplied color, and is clamped
+	opts := &frostutil.DrawImageOptions{}
+	opts.ColorScale.ScaleAlpha(0.5)
+	ass.Equal(color.RGBA{100, 50, 0, 100}, draw(color.RGBA{}, opts))
+	opts.ColorScale.Reset()
+	opts.ColorScale.Scale(2, 0.5, 1, 1)
+	ass.Equal(color.RGBA{255, 50, 0, 200}, draw(color.RGBA{}, opts))
+
+	// other blend modes
+	ass.Equal(color.RGBA{200, 100, 0, 200}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.BlendCopy}))
+	ass.Equal(color.RGBA{}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.BlendClear}))
+	ass.Equal(blue, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.BlendDestination}))
+	ass.Equal(color.RGBA{200, 100, 255, 255}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.BlendLighter}))
+	ass.Equal(color.RGBA{200, 100, 0, 200}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.BlendSourceIn}))
+	ass.Equal(color.RGBA{}, draw(color.RGBA{}, &frostutil.DrawImageOptions{Blend: ebiten.BlendSourceIn}))
+	ass.Equal(color.RGBA{0, 0, 55, 55}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.BlendDestinationOut}))
+	ass.Equal(color.RGBA{200, 100, 255, 255}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.Blend{
+		BlendOperationRGB:   ebiten.BlendOperationMax,
+		BlendOperationAlpha: ebiten.BlendOperationMax,
+	}}))
+	ass.Equal(color.RGBA{0, 0, 255, 55}, draw(blue, &frostutil.DrawImageOptions{Blend: ebiten.Blend{
+		BlendFactorSourceRGB:        ebiten.BlendFactorOne,
+		BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
+		BlendFactorDestinationRGB:   ebiten.BlendFactorOne,
+		BlendFactorDestinationAlpha: ebiten.BlendFactorOne,
+		BlendOperationRGB:           ebiten.BlendOperationReverseSubtract,
+		BlendOperationAlpha:         ebiten.BlendOperationReverseSubtract,
+	}}))
+}
+
+func Test_DrawImageMatchesEbiten(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_DrawImageMatchesEbiten)
+}
+
+func test_DrawImageMatchesEbiten(t *testing.T) {
+	src := makeDrawSprite()
+	eSrc := frostutil.NewEImageFromImage(src, true)
+	background := color.RGBA{30, 60, 90, 255}
+	var rotated, scaled, translated ebiten.GeoM
+	rotated.Translate(-2, -1.5)
+	rotated.Rotate(0.3)
+	rotated.Scale(3, 2)
+	rotated.Translate(10, 8)
+	scaled.Scale(2.5, 3)
+	scaled.Translate(1.5, 0.25)
+	translated.Translate(3, 2)
+	var halfAlpha ebiten.ColorScale
+	halfAlpha.ScaleAlpha(0.5)
+	cases := []struct {
+		name string
+		geoM ebiten.GeoM
+		opts frostutil.DrawImageOptions
+	}{
+		{"translated", translated, frostutil.DrawImageOptions{}},
+		{"rotated nearest", rotated, frostutil.DrawImageOptions{}},
+		{"rotated linear", rotated, frostutil.DrawImageOptions{Filter: ebiten.FilterLinear}},
+		{"scaled linear", scaled, frostutil.DrawImageOptions{Filter: ebiten.FilterLinear}},
+		{"half alpha lighter", translated, frostutil.DrawImageOptions{ColorScale: halfAlpha, Blend: ebiten.BlendLighter}},
+		{"copy", scaled, frostutil.DrawImageOptions{Blend: ebiten.BlendCopy}},
+		{"xor", rotated, frostutil.DrawImageOptions{Blend: ebiten.BlendXor}},
+	}
+	for _, c := range cases {
+		cpu := image.NewRGBA(image.Rect(0, 0, 20, 16))
+		for i := 0; i < len(cpu.Pix); i += 4 {
+			cpu.Pix[i], cpu.Pix[i+1], cpu.Pix[i+2], cpu.Pix[i+3] = background.R, background.G, background.B, background.A
+		}
+		frostutil.DrawImage(cpu, src, c.geoM, &c.opts)
+		gpu := ebiten.NewImage(20, 16)
+		gpu.Fill(background)
+		gpu.DrawImage(eSrc, &ebiten.DrawImageOptions{GeoM: c.geoM, ColorScale: c.opts.ColorScale, Blend: c.opts.Blend, Filter: c.opts.Filter})
+		// GPUs may round differently, and pixels whose centers are right on the edges of rotated images may go either way
+		mismatched := 0
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 20; x++ {
+				r0, g0, b0, a0 := cpu.At(x, y).RGBA()
+				r1, g1, b1, a1 := gpu.At(x, y).RGBA()
+				for _, d := range []int{int(r0>>8) - int(r1>>8), int(g0>>8) - int(g1>>8), int(b0>>8) - int(b1>>8), int(a0>>8) - int(a1>>8)} {
+					if frostutil.Abs(d) > 2 {
+						mismatched++
+						break
+					}
+				}
+			}
+		}
+		assert.LessOrEqual(t, mismatched, 2, c.name)
+	}
+}
@@ -0,0 +1,345 @@
+package frostutil
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// This file implements convolution filters (blurs, sharpen, emboss, and arbitrary kernels) and sprite effects (outlines, drop shadows, and glows)
+// for *image.RGBA and *image.NRGBA images on the CPU. Like Resize, everything happens in alpha-premultiplied float32s (see toPremultipliedFloats),
+// so transparent pixels never bleed their hidden colors into their neighbors. Since the work is done with premultiplied colors, the results don't keep
+// the colors of fully transparent pixels.
+
+// Kernel is a convolution kernel for Convolve.
+type Kernel struct {
+	Width, Height int       // The size of the kernel. Both should be odd, so that the kernel has a center pixel.
+	Weights       []float32 // Width * Height weights, row by row.
+	Bias          float32   // Added to each color component (from 0 to 1) after convolving, such as 0.5 to make an emboss come out gray.
+	// If PreserveAlpha is true, only the colors are convolved, and each pixel keeps its own alpha. Transparent neighbors count as the center pixel's color,
+	// so they don't darken the edges of sprites. This is what kernels whose weights don't add up to 1 (like edge detection or emboss) usually want.
+	// Otherwise, all four alpha-premultiplied components are convolved, which is right for blurs.
+	PreserveAlpha bool
+}
+
+// Convolve returns a copy of img convolved with kernel, with its top-left corner at (0, 0). Pixels past the edges of img are clamped to the edge pixels.
+// If the kernel is empty or its weights don't match its size, it returns an unchanged copy.
+func Convolve[T PixImage](img T, kernel Kernel) T {
+	in, width, height := toPremultipliedFloats(img, false)
+	if kernel.Width <= 0 || kernel.Height <= 0 || len(kernel.Weights) != kernel.Width*kernel.Height {
+		return filteredImage[T](in, width, height)
+	}
+	out := make([]float32, len(in))
+	cx, cy := kernel.Width/2, kernel.Height/2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			o := (y*width + x) * 4
+			ca := in[o+3]
+			// the center pixel's straight color, for PreserveAlpha
+			var cr, cg, cb float32
+			if ca > 0 {
+				cr, cg, cb = in[o]/ca, in[o+1]/ca, in[o+2]/ca
+			}
+			var r, g, b, a float32
+			k := 0
+			for ky := 0; ky < kernel.Height; ky++ {
+				sy := Min(Max(y+ky-cy, 0), height-1)
+				for kx := 0; kx < kernel.Width; kx++ {
+					w := kernel.Weights[k]
+					k++
+					if w == 0 {
+						continue
+					}
+					sx := Min(Max(x+kx-cx, 0), width-1)
+					idx := (sy*width + sx) * 4
+					if kernel.PreserveAlpha {
+						// the neighbor's color composited over the center pixel's color
+						t := 1 - in[idx+3]
+						r += w * (in[idx] + t*cr)
+						g += w * (in[idx+1] + t*cg)
+						b += w * (in[idx+2] + t*cb)
+					} else {
+						r += w * in[idx]
+						g += w * in[idx+1]
+						b += w * in[idx+2]
+						a += w * in[idx+3]
+					}
+				}
+			}
+			if kernel.PreserveAlpha {
+				a = ca
+				r = clampUnit(r+kernel.Bias) * a
+				g = clampUnit(g+kernel.Bias) * a
+				b = clampUnit(b+kernel.Bias) * a
+			} else {
+				a = clampUnit(a)
+				r += kernel.Bias * a
+				g += kernel.Bias * a
+				b += kernel.Bias * a
+			}
+			out[o], out[o+1], out[o+2], out[o+3] = r, g, b, a
+		}
+	}
+	return filteredImage[T](out, width, height)
+}
+
+// GaussianBlur returns a copy of img blurred with a Gaussian filter with the given standard deviation (in pixels), with its top-left corner at (0, 0).
+// The blur is separable, so it's done in a horizontal pass and a vertical pass, and it extends out to 3 standard deviations.
+// If sigma is <= 0, it returns an unchanged copy.
+func GaussianBlur[T PixImage](img T, sigma float64) T {
+	in, width, height := toPremultipliedFloats(img, false)
+	if sigma > 0 {
+		in = convolveSeparable(in, width, height, 4, gaussianWeights(sigma))
+	}
+	return filteredImage[T](in, width, height)
+}
+
+// BoxBlur returns a copy of img where each pixel is the average of the (2*radius+1) x (2*radius+1) square of pixels around it,
+// with its top-left corner at (0, 0). Like GaussianBlur, it's done in two passes. If radius is <= 0, it returns an unchanged copy.
+func BoxBlur[T PixImage](img T, radius int) T {
+	in, width, height := toPremultipliedFloats(img, false)
+	if radius > 0 {
+		weights := make([]float32, 2*radius+1)
+		for i := range weights {
+			weights[i] = 1 / float32(len(weights))
+		}
+		in = convolveSeparable(in, width, height, 4, weights)
+	}
+	return filteredImage[T](in, width, height)
+}
+
+// Sharpen returns a copy of img sharpened by the given amount (where 1 is a typical amount), with its top-left corner at (0, 0).
+// It subtracts amount times each of the four orthogonal neighbors from each pixel, and adds it back to the center pixel. Alpha is preserved.
+func Sharpen[T PixImage](img T, amount float32) T {
+	return Convolve(img, Kernel{Width: 3, Height: 3, Weights: []float32{
+		0, -amount, 0,
+		-amount, 1 + 4*amount, -amount,
+		0, -amount, 0,
+	}, PreserveAlpha: true})
+}
+
+// Emboss returns a copy of img embossed, as though lit from the top left, with its top-left corner at (0, 0).
+// Flat areas become mid-gray, and edges become lighter or darker. Alpha is preserved.
+func Emboss[T PixImage](img T) T {
+	return Convolve(img, Kernel{Width: 3, Height: 3, Weights: []float32{
+		-1, -1, 0,
+		-1, 0, 1,
+		0, 1, 1,
+	}, Bias: 0.5, PreserveAlpha: true})
+}
+
+// gaussianWeights returns the normalized weights of a 1D Gaussian filter with the given standard deviation, extending out to 3 standard deviations.
+func gaussianWeights(sigma float64) []float32 {
+	radius := int(math.Ceil(sigma * 3))
+	weights := make([]float32, 2*radius+1)
+	var sum float64
+	for i := range weights {
+		d := float64(i - radius)
+		w := math.Exp(-d * d / (2 * sigma * sigma))
+		weights[i] = float32(w)
+		sum += w
+	}
+	for i := range weights {
+		weights[i] /= float32(sum)
+	}
+	return weights
+}
+
+// convolveSeparable convolves in (width x height pixels, each made of channels float32s) with weights horizontally and then vertically,
+// and returns the result in a new slice. len(weights) should be odd. Pixels past the edges are clamped to the edge pixels.
+func convolveSeparable(in []float32, width, height, channels int, weights []float32) []float32 {
+	radius := len(weights) / 2
+	tmp := make([]float32, len(in))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			o := (y*width + x) * channels
+			for k, w := range weights {
+				idx := (y*width + Min(Max(x+k-radius, 0), width-1)) * channels
+				for c := 0; c < channels; c++ {
+					tmp[o+c] += w * in[idx+c]
+				}
+			}
+		}
+	}
+	out := make([]float32, len(in))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			o := (y*width + x) * channels
+			for k, w := range weights {
+				idx := (Min(Max(y+k-radius, 0), height-1)*width + x) * channels
+				for c := 0; c < channels; c++ {
+					out[o+c] += w * tmp[idx+c]
+				}
+			}
+		}
+	}
+	return out
+}
+
+// filteredImage converts the alpha-premultiplied float32s (in sRGB) that the filters work on to a new image of type T, like fromPremultipliedFloats does.
+// For NRGBA output, the colors are unmultiplied before they're rounded to bytes, since a blur can leave translucent pixels whose
+// colors would lose precision if they were rounded to premultiplied bytes first.
+func filteredImage[T PixImage](in []float32, width, height int) T {
+	img := newPixImage[T](image.Rect(0, 0, width, height))
+	pix, stride, _, premultiplied := pixBuffer(img)
+	i := 0
+	for y := 0; y < height; y++ {
+		idx := y * stride
+		for x := 0; x < width; x++ {
+			fa := clampUnit(in[i+3])
+			a := byte(fa*255 + 0.5)
+			var r, g, b byte
+			if a == 0 {
+				// fully transparent, so there's no color left to recover
+			} else if premultiplied {
+				// premultiplied components can't be greater than alpha
+				r = Min(byte(clampUnit(in[i])*255+0.5), a)
+				g = Min(byte(clampUnit(in[i+1])*255+0.5), a)
+				b = Min(byte(clampUnit(in[i+2])*255+0.5), a)
+			} else {
+				r = byte(clampUnit(in[i]/fa)*255 + 0.5)
+				g = byte(clampUnit(in[i+1]/fa)*255 + 0.5)
+				b = byte(clampUnit(in[i+2]/fa)*255 + 0.5)
+			}
+			pix[idx], pix[idx+1], pix[idx+2], pix[idx+3] = r, g, b, a
+			idx += 4
+			i += 4
+		}
+	}
+	return img
+}
+
+// effectCanvas holds an image's alpha-premultiplied pixels (from toPremultipliedFloats) with transparent margins around them,
+// so that sprite effects have room to extend past the sprite's original edges.
+type effectCanvas struct {
+	pix           []float32 // 4 per pixel
+	width, height int
+	margin        margins
+}
+
+// margins is the number of transparent pixels an effectCanvas adds on each side of the image.
+type margins struct {
+	left, top, right, bottom int
+}
+
+// newEffectCanvas converts img to premultiplied floats and surrounds it with the given margins.
+func newEffectCanvas[T PixImage](img T, margin margins) *effectCanvas {
+	in, w, h := toPremultipliedFloats(img, false)
+	c := &effectCanvas{width: w + margin.left + margin.right, height: h + margin.top + margin.bottom, margin: margin}
+	c.pix = make([]float32, c.width*c.height*4)
+	for y := 0; y < h; y++ {
+		copy(c.pix[((y+margin.top)*c.width+margin.left)*4:], in[y*w*4:(y+1)*w*4])
+	}
+	return c
+}
+
+// alpha returns the alpha component of each of the canvas's pixels, offset by (dx, dy), so that the pixel at (x, y) in the result comes from (x-dx, y-dy).
+func (c *effectCanvas) alpha(dx, dy int) []float32 {
+	out := make([]float32, c.width*c.height)
+	for y := Max(dy, 0); y < Min(c.height+dy, c.height); y++ {
+		for x := Max(dx, 0); x < Min(c.width+dx, c.width); x++ {
+			out[y*c.width+x] = c.pix[((y-dy)*c.width+(x-dx))*4+3]
+		}
+	}
+	return out
+}
+
+// compositeUnder draws a layer with the given coverage (0 to 1 for each pixel) in the color col underneath the canvas's pixels.
+func (c *effectCanvas) compositeUnder(coverage []float32, col color.Color) {
+	r16, g16, b16, a16 := col.RGBA()
+	r, g, b, a := float32(r16)/0xffff, float32(g16)/0xffff, float32(b16)/0xffff, float32(a16)/0xffff
+	for i, cov := range coverage {
+		if cov <= 0 {
+			continue
+		}
+		cov = clampUnit(cov)
+		idx := i * 4
+		t := 1 - c.pix[idx+3]
+		c.pix[idx] += t * r * cov
+		c.pix[idx+1] += t * g * cov
+		c.pix[idx+2] += t * b * cov
+		c.pix[idx+3] += t * a * cov
+	}
+}
+
+// dilate returns a copy of alpha (width x height) where each value is the maximum value within radius pixels (in a circle).
+func dilate(alpha []float32, width, height, radius int) []float32 {
+	out := make([]float32, len(alpha))
+	r2 := radius*radius + radius // slightly more than radius squared, so that the circle's edges aren't pointy
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var m float32
+			for dy := -radius; dy <= radius; dy++ {
+				sy := y + dy
+				if sy < 0 || sy >= height {
+					continue
+				}
+				for dx := -radius; dx <= radius; dx++ {
+					sx := x + dx
+					if sx < 0 || sx >= width || dx*dx+dy*dy > r2 {
+						continue
+					}
+					m = Max(m, alpha[sy*width+sx])
+				}
+			}
+			out[y*width+x] = m
+		}
+	}
+	return out
+}
+
+// finishEffect converts the canvas to an image of type T, and returns it along with the offset of its top-left corner from the original image's top-left corner.
+func finishEffect[T PixImage](c *effectCanvas) (T, image.Point) {
+	return filteredImage[T](c.pix, c.width, c.height), image.Pt(-c.margin.left, -c.margin.top)
+}
+
+// Outline returns a copy of img with an outline of the given width (in pixels) in the color col drawn around (behind) it.
+// The returned image is width pixels larger on each side than img, so the outline isn't cut off, and offset is the position of its top-left corner
+// relative to img's top-left corner (which is (-width, -width)), so you can draw it at the position you would have drawn img at, plus offset.
+// Partially transparent edge pixels get a partially transparent outline, and the outline shows through partially transparent parts of the sprite.
+func Outline[T PixImage](img T, width int, col color.Color) (out T, offset image.Point) {
+	width = Max(width, 0)
+	c := newEffectCanvas(img, margins{width, width, width, width})
+	c.compositeUnder(dilate(c.alpha(0, 0), c.width, c.height, width), col)
+	return finishEffect[T](c)
+}
+
+// DropShadow returns a copy of img with a shadow of its shape in the color col drawn behind it, moved by (dx, dy) and blurred with
+// a Gaussian filter with standard deviation sigma (or not blurred, if sigma is <= 0).
+// The returned image is larger than img so that the shadow isn't cut off, and offset is the position of its top-left corner relative to img's,
+// so you can draw it at the position you would have drawn img at, plus offset.
+func DropShadow[T PixImage](img T, dx, dy int, sigma float64, col color.Color) (out T, offset image.Point) {
+	blur := 0
+	if sigma > 0 {
+		blur = int(math.Ceil(sigma * 3))
+	}
+	c := newEffectCanvas(img, margins{left: Max(blur-dx, 0), top: Max(blur-dy, 0), right: Max(blur+dx, 0), bottom: Max(blur+dy, 0)})
+	shadow := c.alpha(dx, dy)
+	if sigma > 0 {
+		shadow = convolveSeparable(shadow, c.width, c.height, 1, gaussianWeights(sigma))
+	}
+	c.compositeUnder(shadow, col)
+	return finishEffect[T](c)
+}
+
+// Glow returns a copy of img with a soft glow in the color col around it: its shape is grown by spread pixels (as with Outline), and then
+// blurred with a Gaussian filter with standard deviation sigma. Like Outline and DropShadow, the returned image is larger than img,
+// and offset is the position of its top-left corner relative to img's.
+func Glow[T PixImage](img T, spread int, sigma float64, col color.Color) (out T, offset image.Point) {
+	spread = Max(spread, 0)
+	blur := 0
+	if sigma > 0 {
+		blur = int(math.Ceil(sigma * 3))
+	}
+	m := spread + blur
+	c := newEffectCanvas(img, margins{m, m, m, m})
+	glow := c.alpha(0, 0)
+	if spread > 0 {
+		glow = dilate(glow, c.width, c.height, spread)
+	}
+	if sigma > 0 {
+		glow = convolveSeparable(glow, c.width, c.height, 1, gaussianWeights(sigma))
+	}
+	c.compositeUnder(glow, col)
+	return finishEffect[T](c)
+}
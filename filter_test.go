@@ -0,0 +1,138 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeFilterSprite makes a 10x10 transparent image with an opaque 4x4 green square at (3, 3).
+func makeFilterSprite() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 3; y < 7; y++ {
+		for x := 3; x < 7; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{G: 200, A: 255})
+		}
+	}
+	return img
+}
+
+// assertPureColor checks that every visible pixel in img has the color (r, g, b), so that nothing was darkened by transparent pixels.
+func assertPureColor(ass *assert.Assertions, img *image.NRGBA, r, g, b byte, name string) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i+3] > 2 {
+			// rounding can move the colors of very transparent pixels a little
+			ass.InDelta(int(r), int(img.Pix[i]), 2, name)
+			ass.InDelta(int(g), int(img.Pix[i+1]), 2, name)
+			ass.InDelta(int(b), int(img.Pix[i+2]), 2, name)
+		}
+	}
+}
+
+func Test_Blurs(t *testing.T) {
+	ass := assert.New(t)
+	img := makeFilterSprite()
+
+	blurred := frostutil.GaussianBlur(img, 1)
+	ass.Equal(img.Bounds(), blurred.Bounds())
+	assertPureColor(ass, blurred, 0, 200, 0, "gaussian")
+	ass.Greater(blurred.NRGBAAt(2, 4).A, uint8(0))
+	ass.Less(blurred.NRGBAAt(3, 3).A, uint8(255))
+	// it's symmetric
+	ass.Equal(blurred.NRGBAAt(2, 4), blurred.NRGBAAt(7, 5))
+
+	boxed := frostutil.BoxBlur(img, 1)
+	assertPureColor(ass, boxed, 0, 200, 0, "box")
+	// the corner of the square covers 4 of the 9 pixels
+	ass.InDelta(255*4/9, int(boxed.NRGBAAt(3, 3).A), 1)
+	ass.Equal(uint8(0), boxed.NRGBAAt(1, 1).A)
+
+	ass.Equal(img.Pix, frostutil.GaussianBlur(img, 0).Pix)
+	ass.Equal(img.Pix, frostutil.BoxBlur(img, 0).Pix)
+
+	// blurring a uniform RGBA image changes nothing, edges included
+	rgba := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = 100
+	}
+	ass.Equal(rgba.Pix, frostutil.GaussianBlur(rgba, 2).Pix)
+}
+
+func Test_Convolve(t *testing.T) {
+	ass := assert.New(t)
+	img := makeFilterSprite()
+
+	identity := frostutil.Kernel{Width: 3, Height: 3, Weights: []float32{0, 0, 0, 0, 1, 0, 0, 0, 0}}
+	ass.Equal(img.Pix, frostutil.Convolve(img, identity).Pix)
+	identity.PreserveAlpha = true
+	ass.Equal(img.Pix, frostutil.Convolve(img, identity).Pix)
+
+	// shifting one pixel to the right
+	shift := frostutil.Kernel{Width: 3, Height: 1, Weights: []float32{1, 0, 0}}
+	shifted := frostutil.Convolve(img, shift)
+	ass.Equal(uint8(0), shifted.NRGBAAt(3, 3).A)
+	ass.Equal(color.NRGBA{G: 200, A: 255}, shifted.NRGBAAt(7, 3))
+
+	// sharpening a flat sprite doesn't darken its edges, and keeps its alpha
+	sharpened := frostutil.Sharpen(img, 1)
+	ass.Equal(img.Pix, sharpened.Pix)
+
+	embossed := frostutil.Emboss(img)
+	// flat areas are gray, and alpha is untouched
+	for _, c := range embossed.Pix[(4*10+4)*4 : (4*10+4)*4+3] {
+		ass.InDelta(128, int(c), 1)
+	}
+	for i := 3; i < len(img.Pix); i += 4 {
+		ass.Equal(img.Pix[i], embossed.Pix[i])
+	}
+
+	// a bad kernel gives a copy
+	ass.Equal(img.Pix, frostutil.Convolve(img, frostutil.Kernel{Width: 3, Height: 3}).Pix)
+}
+
+func Test_SpriteEffects(t *testing.T) {
+	ass := assert.New(t)
+	img := makeFilterSprite()
+	red := color.NRGBA{R: 255, A: 255}
+
+	outlined, offset := frostutil.Outline(img, 2, red)
+	ass.Equal(image.Rect(0, 0, 14, 14), outlined.Bounds())
+	ass.Equal(image.Pt(-2, -2), offset)
+	// the sprite is on top, and the outline is around it
+	ass.Equal(color.NRGBA{G: 200, A: 255}, outlined.NRGBAAt(5, 5))
+	ass.Equal(red, outlined.NRGBAAt(3, 5))
+	ass.Equal(red, outlined.NRGBAAt(4, 5))
+	ass.Equal(uint8(0), outlined.NRGBAAt(2, 5).A)
+	ass.Equal(red, outlined.NRGBAAt(4, 4))
+	ass.Equal(uint8(0), outlined.NRGBAAt(3, 3).A)
+
+	shadowed, offset := frostutil.DropShadow(img, 2, 3, 0, color.NRGBA{A: 128})
+	ass.Equal(image.Rect(0, 0, 12, 13), shadowed.Bounds())
+	ass.Equal(image.Pt(0, 0), offset)
+	ass.Equal(color.NRGBA{G: 200, A: 255}, shadowed.NRGBAAt(3, 3))
+	ass.Equal(color.NRGBA{A: 128}, shadowed.NRGBAAt(8, 9))
+	ass.Equal(uint8(0), shadowed.NRGBAAt(4, 2).A)
+
+	shadowed, offset = frostutil.DropShadow(img, -1, 0, 1, color.Black)
+	ass.Equal(image.Pt(-4, -3), offset)
+	ass.Equal(image.Rect(0, 0, 16, 16), shadowed.Bounds())
+	ass.Equal(color.NRGBA{G: 200, A: 255}, shadowed.NRGBAAt(7, 6))
+	ass.Greater(shadowed.NRGBAAt(5, 6).A, uint8(0))
+
+	glowed, offset := frostutil.Glow(img, 1, 1, color.NRGBA{R: 255, G: 255, A: 255})
+	ass.Equal(image.Pt(-4, -4), offset)
+	ass.Equal(image.Rect(0, 0, 18, 18), glowed.Bounds())
+	ass.Equal(color.NRGBA{G: 200, A: 255}, glowed.NRGBAAt(8, 8))
+	ass.Greater(glowed.NRGBAAt(5, 8).A, uint8(0))
+	ass.Equal(uint8(0), glowed.NRGBAAt(0, 0).A)
+
+	// the effects work on RGBA images too
+	rgba := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	rgba.SetRGBA(0, 0, color.RGBA{B: 255, A: 255})
+	outlinedRGBA, _ := frostutil.Outline(rgba, 1, red)
+	ass.Equal(color.RGBA{R: 255, A: 255}, outlinedRGBA.RGBAAt(1, 0))
+	ass.Equal(color.RGBA{B: 255, A: 255}, outlinedRGBA.RGBAAt(1, 1))
+}
@@ -5,6 +5,7 @@ go 1.22
 require (
 	github.com/hajimehoshi/ebiten/v2 v2.7.4
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/image v0.18.0
 )
 
 require (
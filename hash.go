@@ -0,0 +1,158 @@
+package frostutil
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"math"
+	"math/bits"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ImageHash is a 64-bit perceptual hash of an image, from AverageHash, DifferenceHash, or PerceptualHash.
+// Images that look alike have hashes that differ in only a few bits, which Distance counts.
+// Only compare hashes made by the same function.
+type ImageHash uint64
+
+// Distance returns the Hamming distance between two hashes: the number of bits which differ. 0 means the images are (probably) the same,
+// and as a rule of thumb, up to about 10 means they're similar.
+func (h ImageHash) Distance(other ImageHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+// hashGrayscale shrinks img to width x height with a box filter and returns the luma of each pixel, row by row.
+// The colors are alpha-premultiplied first (as if the image were drawn over black), so the hidden colors of transparent pixels don't matter.
+// *ebiten.Images are read with NewImageFromEImage.
+func hashGrayscale(img image.Image, width, height int) []float64 {
+	img = unwrapSnapshot(img)
+	var rgba *image.RGBA
+	switch xImg := img.(type) {
+	case *image.RGBA:
+		rgba = xImg
+	case *ebiten.Image:
+		rgba = NewImageFromEImage(xImg)
+	default:
+		bounds := img.Bounds()
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+	gray := make([]float64, width*height)
+	if rgba.Bounds().Empty() {
+		return gray
+	}
+	small := Resize(rgba, width, height, ResizeOptions{Filter: FilterBox})
+	for i := range gray {
+		p := small.Pix[i*4 : i*4+3]
+		gray[i] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+	}
+	return gray
+}
+
+// AverageHash returns the aHash of img: it's shrunk to 8x8 and turned to grayscale, and each bit is set if that pixel is brighter than the average.
+// It's fast, but less discriminating than DifferenceHash and PerceptualHash.
+func AverageHash(img image.Image) (hash ImageHash) {
+	gray := hashGrayscale(img, 8, 8)
+	var mean float64
+	for _, v := range gray {
+		mean += v
+	}
+	mean /= float64(len(gray))
+	for i, v := range gray {
+		if v > mean {
+			hash |= 1 << i
+		}
+	}
+	return
+}
+
+// DifferenceHash returns the dHash of img: it's shrunk to 9x8 and turned to grayscale, and each bit is set if that pixel is darker than the one to its right.
+// It tracks gradients, so it isn't thrown off by overall changes in brightness.
+func DifferenceHash(img image.Image) (hash ImageHash) {
+	gray := hashGrayscale(img, 9, 8)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if gray[y*9+x] < gray[y*9+x+1] {
+				hash |= 1 << (y*8 + x)
+			}
+		}
+	}
+	return
+}
+
+// pHashSize is the size PerceptualHash shrinks images to before taking their DCT.
+const pHashSize = 32
+
+// pHashCosines holds cos((2x+1) * u * pi / (2 * pHashSize)) at [u][x], for the lowest 8 frequencies u.
+var pHashCosines = func() (table [8][pHashSize]float64) {
+	for u := range table {
+		for x := range table[u] {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * pHashSize))
+		}
+	}
+	return
+}()
+
+// PerceptualHash returns the pHash of img: it's shrunk to 32x32 and turned to grayscale, and the 8x8 lowest frequencies of its discrete cosine transform
+// are compared to their median, setting each bit whose frequency is above it. The DC term (the average brightness) is left out of the median.
+// It's the slowest of the three, but the most robust against small changes like scaling, blurring, and recompression.
+func PerceptualHash(img image.Image) (hash ImageHash) {
+	gray := hashGrayscale(img, pHashSize, pHashSize)
+	// the DCT is separable, so we transform the rows and then the columns, only keeping the 8 lowest frequencies each time
+	var rows [pHashSize][8]float64
+	for y := 0; y < pHashSize; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < pHashSize; x++ {
+				sum += gray[y*pHashSize+x] * pHashCosines[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+	var dct [64]float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < pHashSize; y++ {
+				sum += rows[y][u] * pHashCosines[v][y]
+			}
+			dct[v*8+u] = sum
+		}
+	}
+	sorted := make([]float64, 63)
+	copy(sorted, dct[1:])
+	sort.Float64s(sorted)
+	median := (sorted[31] + sorted[32]) / 2
+	for i, v := range dct {
+		if v > median {
+			hash |= 1 << i
+		}
+	}
+	return
+}
+
+// ContentHash returns a SHA-256 hash of img's size and pixels, for finding exact duplicates. The pixels are normalized to NRGBA with NewNRGBAFromImage
+// (after NewImageFromEImage, for *ebiten.Images), and fully transparent pixels are treated as transparent black, so the hash doesn't depend on the
+// image's type, stride, bounds origin, or the hidden colors of transparent pixels (which alpha-premultiplied images can't keep anyways).
+func ContentHash(img image.Image) [sha256.Size]byte {
+	if eImg, ok := img.(*ebiten.Image); ok {
+		img = NewImageFromEImage(eImg)
+	}
+	nrgba := NewNRGBAFromImage(img)
+	h := sha256.New()
+	var size [8]byte
+	binary.BigEndian.PutUint32(size[:4], uint32(nrgba.Rect.Dx()))
+	binary.BigEndian.PutUint32(size[4:], uint32(nrgba.Rect.Dy()))
+	h.Write(size[:])
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		if nrgba.Pix[i+3] == 0 {
+			nrgba.Pix[i], nrgba.Pix[i+1], nrgba.Pix[i+2] = 0, 0, 0
+		}
+	}
+	h.Write(nrgba.Pix)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
@@ -0,0 +1,99 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PerceptualHashes(t *testing.T) {
+	ass := assert.New(t)
+	// a diagonal gradient with a bright circle in the upper left, so that the same picture can be made at different sizes
+	makeImage := func(w, h int) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				v := byte((x + y) * 200 / (w + h))
+				dx, dy := x-w/3, y-h/3
+				if dx*dx+dy*dy < w*w/25 {
+					v = 250
+				}
+				img.SetNRGBA(x, y, color.NRGBA{v, v / 2, 255 - v, 255})
+			}
+		}
+		return img
+	}
+	img := makeImage(64, 64)
+	// a slightly brighter copy, a scaled copy, and an unrelated image (the same one, flipped)
+	brighter := frostutil.CopyImage(img, false).(*image.NRGBA)
+	for i := 0; i < len(brighter.Pix); i += 4 {
+		brighter.Pix[i] = byte(frostutil.Min(int(brighter.Pix[i])+6, 255))
+	}
+	scaled := makeImage(100, 100)
+	other := frostutil.Rotate180(img)
+
+	for _, hashFunc := range []struct {
+		name string
+		hash func(image.Image) frostutil.ImageHash
+	}{{"aHash", frostutil.AverageHash}, {"dHash", frostutil.DifferenceHash}, {"pHash", frostutil.PerceptualHash}} {
+		h := hashFunc.hash(img)
+		ass.NotZero(h, hashFunc.name)
+		ass.Equal(0, h.Distance(hashFunc.hash(img)), hashFunc.name)
+		ass.LessOrEqual(h.Distance(hashFunc.hash(brighter)), 4, hashFunc.name)
+		ass.LessOrEqual(h.Distance(hashFunc.hash(scaled)), 6, hashFunc.name)
+		ass.Greater(h.Distance(hashFunc.hash(other)), 16, hashFunc.name)
+		// the image type and origin don't matter
+		rgba := image.NewRGBA(image.Rect(5, 5, 69, 69))
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				rgba.Set(x+5, y+5, img.At(x, y))
+			}
+		}
+		ass.Equal(h, hashFunc.hash(rgba), hashFunc.name)
+	}
+	ass.Equal(3, frostutil.ImageHash(0b1011).Distance(0))
+}
+
+func Test_ContentHash(t *testing.T) {
+	ass := assert.New(t)
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 7)
+	}
+	h := frostutil.ContentHash(img)
+
+	// a sub-image with a different stride and origin, but the same pixels
+	big := image.NewNRGBA(image.Rect(0, 0, 30, 30))
+	for y := 0; y < 10; y++ {
+		copy(big.Pix[big.PixOffset(5, y+7):], img.Pix[img.PixOffset(0, y):img.PixOffset(0, y+1)])
+	}
+	ass.Equal(h, frostutil.ContentHash(big.SubImage(image.Rect(5, 7, 25, 17))))
+
+	// the hidden colors of transparent pixels don't matter, but visible changes do
+	img.SetNRGBA(0, 0, color.NRGBA{})
+	h = frostutil.ContentHash(img)
+	img.SetNRGBA(0, 0, color.NRGBA{R: 9})
+	ass.Equal(h, frostutil.ContentHash(img))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 9, A: 1})
+	ass.NotEqual(h, frostutil.ContentHash(img))
+
+	// the size matters, even when the pixels are all the same
+	ass.NotEqual(frostutil.ContentHash(image.NewNRGBA(image.Rect(0, 0, 2, 8))), frostutil.ContentHash(image.NewNRGBA(image.Rect(0, 0, 4, 4))))
+}
+
+func Test_HashEImage(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_HashEImage)
+}
+
+func test_HashEImage(t *testing.T) {
+	ass := assert.New(t)
+	img := GetTestImageNRGBA(Alpha_FF)
+	// the *ebiten.Image is read with NewImageFromEImage
+	eImg := frostutil.NewEImageFromImage(img, false)
+	ass.Equal(frostutil.ContentHash(img), frostutil.ContentHash(eImg))
+	ass.Equal(frostutil.PerceptualHash(img), frostutil.PerceptualHash(eImg))
+	ass.Equal(frostutil.DifferenceHash(img), frostutil.DifferenceHash(eImg))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"image"
 	"image/color"
+	"image/draw"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -25,6 +26,127 @@ func NewImageFromEImage(eImg *ebiten.Image) (img *image.RGBA) {
 	return
 }
 
+// NewNRGBAFromImage converts any image to a new *image.NRGBA with its top-left corner at (0, 0), removing the alpha premultiplication
+// with UnmultiplyAlphaBytes (or ToNRGBA, for image types we don't have pixel buffer access to), so that non-zero color components are preserved
+// where the alpha component is zero, unlike when converting with color.NRGBAModel or (*image.NRGBA).Set.
+// *ebiten.Images are read with a single ReadPixels call, and *image.RGBA and *image.NRGBA images are converted straight from their pixel buffers.
+func NewNRGBAFromImage(img image.Image) (ret *image.NRGBA) {
+	img = unwrapSnapshot(img)
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+	ret = image.NewNRGBA(image.Rect(0, 0, width, height))
+	var iPix []byte
+	iStride := width << 2
+	if eImg, ok := img.(*ebiten.Image); ok {
+		iPix = make([]byte, 4*width*height)
+		eImg.ReadPixels(iPix)
+	} else if iImg, ok := img.(*image.RGBA); ok {
+		iPix = iImg.Pix[iImg.PixOffset(bounds.Min.X, bounds.Min.Y):]
+		iStride = iImg.Stride
+	} else if iImg, ok := img.(*image.NRGBA); ok {
+		// it's already NRGBA, so we only need to copy it
+		for y := 0; y < height; y++ {
+			iIdx := iImg.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(ret.Pix[y*ret.Stride:y*ret.Stride+iStride], iImg.Pix[iIdx:iIdx+iStride])
+		}
+		return
+	} else {
+		SlowImageCopy(ret, img)
+		return
+	}
+	for y := 0; y < height; y++ {
+		iIdx := y * iStride
+		oIdx := y * ret.Stride
+		for x := 0; x < width; x++ {
+			ret.Pix[oIdx], ret.Pix[oIdx+1], ret.Pix[oIdx+2], ret.Pix[oIdx+3] = UnmultiplyAlphaBytes(iPix[iIdx], iPix[iIdx+1], iPix[iIdx+2], iPix[iIdx+3])
+			iIdx += 4
+			oIdx += 4
+		}
+	}
+	return
+}
+
+// PixImage is satisfied by the two image types whose pixel buffers we know how to work with directly: *image.RGBA and *image.NRGBA.
+// Both store 4 bytes per pixel in R, G, B, A order, but *image.RGBA's color components are alpha-premultiplied and *image.NRGBA's aren't.
+type PixImage interface {
+	*image.RGBA | *image.NRGBA
+}
+
+// pixBuffer returns img's pixel buffer, stride, and bounds, and whether its colors are alpha-premultiplied.
+func pixBuffer[T PixImage](img T) (pix []byte, stride int, bounds image.Rectangle, premultiplied bool) {
+	switch xImg := any(img).(type) {
+	case *image.RGBA:
+		return xImg.Pix, xImg.Stride, xImg.Rect, true
+	case *image.NRGBA:
+		return xImg.Pix, xImg.Stride, xImg.Rect, false
+	}
+	return
+}
+
+// newPixImage creates a new image of the same type as T with the given bounds.
+func newPixImage[T PixImage](bounds image.Rectangle) (img T) {
+	switch any(img).(type) {
+	case *image.RGBA:
+		return any(image.NewRGBA(bounds)).(T)
+	case *image.NRGBA:
+		return any(image.NewNRGBA(bounds)).(T)
+	}
+	return
+}
+
+// alphaValues returns the alpha component of every pixel of img, row by row, with no padding between rows, along with img's width and height.
+// *image.RGBA, *image.NRGBA, *image.Alpha, and *image.Gray images are read straight from their pixel buffers, and *ebiten.Images are read
+// with a single ReadPixels call. Anything else is read pixel by pixel with At.
+func alphaValues(img image.Image) (alpha []byte, width, height int) {
+	img = unwrapSnapshot(img)
+	bounds := img.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+	alpha = make([]byte, width*height)
+	var pix []byte
+	stride := width << 2
+	switch xImg := img.(type) {
+	case *ebiten.Image:
+		pix = make([]byte, 4*width*height)
+		xImg.ReadPixels(pix)
+	case *image.RGBA:
+		pix = xImg.Pix[xImg.PixOffset(bounds.Min.X, bounds.Min.Y):]
+		stride = xImg.Stride
+	case *image.NRGBA:
+		pix = xImg.Pix[xImg.PixOffset(bounds.Min.X, bounds.Min.Y):]
+		stride = xImg.Stride
+	case *image.Alpha:
+		for y := 0; y < height; y++ {
+			idx := xImg.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(alpha[y*width:(y+1)*width], xImg.Pix[idx:idx+width])
+		}
+		return
+	case *image.Gray:
+		// Gray images are opaque
+		for i := range alpha {
+			alpha[i] = 0xff
+		}
+		return
+	default:
+		i := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				_, _, _, alpha[i] = ToNRGBA(img.At(x, y))
+				i++
+			}
+		}
+		return
+	}
+	for y := 0; y < height; y++ {
+		idx := y*stride + 3
+		for x := 0; x < width; x++ {
+			alpha[y*width+x] = pix[idx]
+			idx += 4
+		}
+	}
+	return
+}
+
 // NewEImageFromImage converts an image.Image to an *ebiten.Image by creating a new *ebiten.Image and
 // writing the image data into it (with the new WritePixels method introduced in ebitengine 2.4.*).
 // If mipmaps is true, the *ebiten.Image is created with mipmaps.
@@ -34,6 +156,7 @@ func NewImageFromEImage(eImg *ebiten.Image) (img *image.RGBA) {
 // I originally wrote this because ebiten.NewImageFromImage was corrupting the pixel data of the source images passed to it
 // (I don't know if it still does, but if so, calling this instead should prevent it).
 func NewEImageFromImage(img image.Image, mipmaps bool) (ret *ebiten.Image) {
+	img = unwrapSnapshot(img)
 	left := img.Bounds().Min.X
 	top := img.Bounds().Min.Y
 	width := img.Bounds().Max.X - left
@@ -85,6 +208,7 @@ func NewEImageFromImage(img image.Image, mipmaps bool) (ret *ebiten.Image) {
 // using At and Set, which is pretty slow.
 // CopyImage returns the copy it creates.
 func CopyImage(img image.Image, mipmaps bool) (ret image.Image) {
+	img = unwrapSnapshot(img)
 	left := img.Bounds().Min.X
 	top := img.Bounds().Min.Y
 	width := img.Bounds().Max.X - left
@@ -114,58 +238,125 @@ func CopyImage(img image.Image, mipmaps bool) (ret image.Image) {
 		ret = oImg
 	} else {
 		oImg := image.NewRGBA(rect)
-		SlowImageCopy(oImg, iImg)
+		SlowImageCopy(oImg, img)
 		ret = oImg
 	}
 	return
 }
 
+// toNRGBA64 is the 16-bit version of ToNRGBA, for SlowImageCopy's 16-bit destinations: it converts c to a color.NRGBA64 without reducing it to
+// 8 bits per component. Like ToNRGBA, color.NRGBA and color.NRGBA64 colors are used as they are, and other colors are only unmultiplied when their
+// alpha is neither 0 nor 0xffff, so non-zero color components are preserved where alpha is zero.
+func toNRGBA64(c color.Color) color.NRGBA64 {
+	switch xc := c.(type) {
+	case color.NRGBA64:
+		return xc
+	case color.NRGBA:
+		return color.NRGBA64{R: uint16(xc.R) * 0x101, G: uint16(xc.G) * 0x101, B: uint16(xc.B) * 0x101, A: uint16(xc.A) * 0x101}
+	}
+	r, g, b, a := c.RGBA()
+	if a != 0 && a != 0xffff {
+		r = r * 0xffff / a
+		g = g * 0xffff / a
+		b = b * 0xffff / a
+	}
+	return color.NRGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+}
+
 // CopyImageLines copies pixel data from iPix to oPix line by line.
 // oPix should be the output image's pixel data buffer, oStride should be its Stride,
 // and iPix and iStride should be the same for the input image.
+// It stops when it runs out of lines in either buffer, so iPix can be a sub-image's pixel buffer, which continues on to the end of the parent image's buffer.
 func CopyImageLines(oPix []byte, oStride int, iPix []byte, iStride int) {
-	oIdx := 0
 	lowerStride := Min(oStride, iStride)
-	for iIdx := 0; iIdx < len(iPix); iIdx += iStride {
-		copy(oPix[oIdx:oIdx+lowerStride], iPix[iIdx:iIdx+lowerStride])
-		oIdx += oStride
+	for iIdx, oIdx := 0, 0; iIdx < len(iPix) && oIdx < len(oPix); iIdx, oIdx = iIdx+iStride, oIdx+oStride {
+		copy(oPix[oIdx:Min(oIdx+lowerStride, len(oPix))], iPix[iIdx:Min(iIdx+lowerStride, len(iPix))])
 	}
 }
 
-// SlowImageCopy copies pixel data from iImg to oImg pixel by pixel using (Image).At and (Image).Set. It's called by CopyImage or NewEImageFromImage
+// SlowImageCopy copies pixel data from iImg to oImg pixel by pixel using (Image).At, and is called by CopyImage or NewEImageFromImage
 // if iImg isn't an *ebiten.Image, *image.NRGBA, or *image.RGBA.
-// Currently, oImg must still be one of those three for this to work, since the image.Image interface doesn't have a Set method. If it isn't one of those,
-// this returns an error.
-// For each pixel of each row, it uses the At method to get the pixel color from the source image, and Set to set it on the output image.
-// Warning: (*image.NRGBA).Set sets the pixel's color components to 0 when the alpha component is 0,
-// even if the color components aren't 0 in the color which was returned by At.
-// This causes any tests which attempt to use At and Set to copy pixels with non-zero color components and a zero alpha component to show failures.
-// The same is true for *(image.NRGBA64).Set.
-func SlowImageCopy(oImg, iImg image.Image) (err error) {
+// oImg can be any draw.Image. If it's an *image.RGBA, *image.NRGBA, or *ebiten.Image, the pixel data is written straight into its pixel buffer
+// (or, for *ebiten.Image, collected into a buffer which is written with a single WritePixels call), which is a good deal faster than calling Set on each pixel.
+// Otherwise, each pixel's color is passed to oImg.Set.
+// An *ebiten.Image iImg is read with a single ReadPixels call (into an EImageSnapshot) instead of with At, and an EImageSnapshot oImg is written to
+// like an *image.RGBA (so call its Flush method afterwards to write it back to its *ebiten.Image).
+// Where the destination color model isn't alpha-premultiplied (*image.NRGBA, or any draw.Image whose ColorModel is color.NRGBAModel or color.NRGBA64Model),
+// the colors are converted with ToNRGBA (or, for color.NRGBA64Model, a 16-bit version of it, so 16-bit sources keep their precision),
+// so non-zero color components are preserved when the alpha component is zero.
+// Note that (*image.NRGBA).Set and (*image.NRGBA64).Set would still convert colors which aren't already color.NRGBA or color.NRGBA64, so we always
+// hand them one of those.
+// Premultiplied destinations (*image.RGBA, *ebiten.Image, and most others) can't represent color without alpha, so those get the standard conversion.
+// It returns an error if either image is nil.
+func SlowImageCopy(oImg draw.Image, iImg image.Image) (err error) {
+	if oImg == nil || iImg == nil {
+		return errors.New("SlowImageCopy was passed a nil image")
+	}
+	if eImg, ok := iImg.(*ebiten.Image); ok {
+		// reading it once is much faster than calling At on every pixel
+		iImg = NewEImageSnapshot(eImg)
+	}
+	iImg = unwrapSnapshot(iImg)
+	if snap, ok := oImg.(*EImageSnapshot); ok {
+		oImg = snap.RGBA
+	}
 	left := iImg.Bounds().Min.X
 	top := iImg.Bounds().Min.Y
-	width := oImg.Bounds().Dx()
-	height := oImg.Bounds().Dy()
+	oBounds := oImg.Bounds()
+	width := Min(oBounds.Dx(), iImg.Bounds().Dx())
+	height := Min(oBounds.Dy(), iImg.Bounds().Dy())
 	if xOImg, ok := oImg.(*image.RGBA); ok {
 		for y := 0; y < height; y++ {
+			idx := xOImg.PixOffset(oBounds.Min.X, oBounds.Min.Y+y)
 			for x := 0; x < width; x++ {
-				xOImg.Set(x, y, iImg.At(x+left, y+top))
+				r, g, b, a := iImg.At(x+left, y+top).RGBA()
+				xOImg.Pix[idx] = byte(r >> 8)
+				xOImg.Pix[idx+1] = byte(g >> 8)
+				xOImg.Pix[idx+2] = byte(b >> 8)
+				xOImg.Pix[idx+3] = byte(a >> 8)
+				idx += 4
 			}
 		}
 	} else if xOImg, ok := oImg.(*image.NRGBA); ok {
 		for y := 0; y < height; y++ {
+			idx := xOImg.PixOffset(oBounds.Min.X, oBounds.Min.Y+y)
 			for x := 0; x < width; x++ {
-				xOImg.Set(x, y, iImg.At(x+left, y+top))
+				xOImg.Pix[idx], xOImg.Pix[idx+1], xOImg.Pix[idx+2], xOImg.Pix[idx+3] = ToNRGBA(iImg.At(x+left, y+top))
+				idx += 4
 			}
 		}
 	} else if xOImg, ok := oImg.(*ebiten.Image); ok {
+		pixelBytes := make([]byte, width*height*4)
+		idx := 0
 		for y := 0; y < height; y++ {
 			for x := 0; x < width; x++ {
-				xOImg.Set(x, y, iImg.At(x+left, y+top))
+				r, g, b, a := iImg.At(x+left, y+top).RGBA()
+				pixelBytes[idx] = byte(r >> 8)
+				pixelBytes[idx+1] = byte(g >> 8)
+				pixelBytes[idx+2] = byte(b >> 8)
+				pixelBytes[idx+3] = byte(a >> 8)
+				idx += 4
 			}
 		}
+		if width != oBounds.Dx() || height != oBounds.Dy() {
+			// WritePixels always writes to the whole image, so we have to narrow it down to the area we're copying to.
+			xOImg = xOImg.SubImage(image.Rect(oBounds.Min.X, oBounds.Min.Y, oBounds.Min.X+width, oBounds.Min.Y+height)).(*ebiten.Image)
+		}
+		xOImg.WritePixels(pixelBytes)
 	} else {
-		err = errors.New("SlowImageCopy only knows how to write to images of type *ebiten.Image, *image.NRGBA, and *image.RGBA. We got a different type instead.")
+		model := oImg.ColorModel()
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				c := iImg.At(x+left, y+top)
+				switch model {
+				case color.NRGBAModel:
+					c = ToNRGBA_Color(c)
+				case color.NRGBA64Model:
+					c = toNRGBA64(c)
+				}
+				oImg.Set(x+oBounds.Min.X, y+oBounds.Min.Y, c)
+			}
+		}
 	}
 	return
 }
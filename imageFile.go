@@ -0,0 +1,377 @@
+package frostutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/bmp"
+)
+
+// ImageFormat identifies one of the image file formats that LoadImage and SaveImage know how to handle.
+type ImageFormat int
+
+const (
+	ImageFormatUnknown ImageFormat = iota // The format couldn't be determined, or isn't one we support
+	ImageFormatPNG
+	ImageFormatJPEG
+	ImageFormatGIF
+	ImageFormatBMP
+	ImageFormatQOI
+	ImageFormatTGA
+	NumImageFormats
+)
+
+// imageCodec holds everything we need to know about an image format to detect, decode, and encode it.
+type imageCodec struct {
+	name       string
+	extensions []string                 // lowercase, including the leading '.'
+	match      func(header []byte) bool // reports whether header (the first bytes of a file) looks like this format
+	decode     func(io.Reader) (image.Image, error)
+	encode     func(io.Writer, image.Image) error
+}
+
+// headerLen is how many bytes we peek at to detect the format of an image.
+const headerLen = 32
+
+// imageCodecs is indexed by ImageFormat. Detection tries them in order, so formats with short or weak magic numbers should go last.
+var imageCodecs = [NumImageFormats]imageCodec{
+	ImageFormatUnknown: {name: "unknown"},
+	ImageFormatPNG: {
+		name:       "png",
+		extensions: []string{".png"},
+		match:      func(h []byte) bool { return bytes.HasPrefix(h, []byte("\x89PNG\r\n\x1a\n")) },
+		decode:     png.Decode,
+		encode:     png.Encode,
+	},
+	ImageFormatJPEG: {
+		name:       "jpeg",
+		extensions: []string{".jpg", ".jpeg"},
+		match:      func(h []byte) bool { return bytes.HasPrefix(h, []byte("\xff\xd8\xff")) },
+		decode:     jpeg.Decode,
+		encode:     func(w io.Writer, img image.Image) error { return jpeg.Encode(w, img, nil) },
+	},
+	ImageFormatGIF: {
+		name:       "gif",
+		extensions: []string{".gif"},
+		match: func(h []byte) bool {
+			return bytes.HasPrefix(h, []byte("GIF87a")) || bytes.HasPrefix(h, []byte("GIF89a"))
+		},
+		decode: gif.Decode,
+		encode: func(w io.Writer, img image.Image) error { return gif.Encode(w, img, nil) },
+	},
+	ImageFormatBMP: {
+		name:       "bmp",
+		extensions: []string{".bmp"},
+		match:      func(h []byte) bool { return bytes.HasPrefix(h, []byte("BM")) },
+		decode:     bmp.Decode,
+		encode:     bmp.Encode,
+	},
+	ImageFormatQOI: {
+		name:       "qoi",
+		extensions: []string{".qoi"},
+		match:      func(h []byte) bool { return bytes.HasPrefix(h, []byte(qoiMagic)) },
+		decode:     DecodeQOI,
+		encode:     EncodeQOI,
+	},
+	ImageFormatTGA: {
+		name:       "tga",
+		extensions: []string{".tga"},
+		match:      isTGAHeader, // TGA has no magic number, so this only checks that the header is sensible
+		decode:     DecodeTGA,
+		encode:     func(w io.Writer, img image.Image) error { return EncodeTGA(w, img, nil) },
+	},
+}
+
+// String returns the short lowercase name of the format, e.g. "png".
+func (format ImageFormat) String() string {
+	if format < 0 || format >= NumImageFormats {
+		return imageCodecs[ImageFormatUnknown].name
+	}
+	return imageCodecs[format].name
+}
+
+// DetectImageFormat looks at the first bytes of an image file and returns the format it appears to be in,
+// or ImageFormatUnknown if it doesn't look like anything we can decode.
+func DetectImageFormat(header []byte) ImageFormat {
+	for format := ImageFormatUnknown + 1; format < NumImageFormats; format++ {
+		if imageCodecs[format].match != nil && imageCodecs[format].match(header) {
+			return format
+		}
+	}
+	return ImageFormatUnknown
+}
+
+// ImageFormatFromFilename returns the format matching filename's extension (case-insensitively), or ImageFormatUnknown if we don't recognize it.
+func ImageFormatFromFilename(filename string) ImageFormat {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for format := ImageFormatUnknown + 1; format < NumImageFormats; format++ {
+		for _, codecExt := range imageCodecs[format].extensions {
+			if ext == codecExt {
+				return format
+			}
+		}
+	}
+	return ImageFormatUnknown
+}
+
+// UnknownImageFormatError is returned when we can't tell what format an image is in, or we can't tell what format to save it in.
+// Name is the file's name or path, if there is one.
+type UnknownImageFormatError struct {
+	Name string
+}
+
+func (e *UnknownImageFormatError) Error() string {
+	if len(e.Name) > 0 {
+		return fmt.Sprintf("%v: unknown or unsupported image format", e.Name)
+	}
+	return "unknown or unsupported image format"
+}
+
+// ImageDecodeError is returned when an image's format was recognized but the decoder failed. Err is the error the decoder returned.
+// Name is the file's name or path, if there is one.
+type ImageDecodeError struct {
+	Name   string
+	Format ImageFormat
+	Err    error
+}
+
+func (e *ImageDecodeError) Error() string {
+	if len(e.Name) > 0 {
+		return fmt.Sprintf("%v: failed to decode %v image: %v", e.Name, e.Format, e.Err)
+	}
+	return fmt.Sprintf("failed to decode %v image: %v", e.Format, e.Err)
+}
+
+func (e *ImageDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ImageEncodeError is returned when the encoder fails while saving an image. Err is the error the encoder (or the writer) returned.
+// Name is the file's name or path, if there is one.
+type ImageEncodeError struct {
+	Name   string
+	Format ImageFormat
+	Err    error
+}
+
+func (e *ImageEncodeError) Error() string {
+	if len(e.Name) > 0 {
+		return fmt.Sprintf("%v: failed to encode %v image: %v", e.Name, e.Format, e.Err)
+	}
+	return fmt.Sprintf("failed to encode %v image: %v", e.Format, e.Err)
+}
+
+func (e *ImageEncodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeImage detects the format of the image data in r from its first few bytes, and decodes it with the matching decoder.
+// If the format isn't recognized, it returns an *UnknownImageFormatError. If decoding fails, it returns an *ImageDecodeError.
+func DecodeImage(r io.Reader) (img image.Image, format ImageFormat, err error) {
+	return decodeImage(r, "")
+}
+
+// decodeImage implements DecodeImage. name is only used in errors.
+func decodeImage(r io.Reader, name string) (img image.Image, format ImageFormat, err error) {
+	br := bufio.NewReader(r)
+	// Peek returns an error if the data is shorter than headerLen, but we still want to look at what it did return.
+	header, _ := br.Peek(headerLen)
+	format = DetectImageFormat(header)
+	if format == ImageFormatUnknown {
+		err = &UnknownImageFormatError{Name: name}
+		return
+	}
+	img, err = imageCodecs[format].decode(br)
+	if err != nil {
+		img = nil
+		err = &ImageDecodeError{Name: name, Format: format, Err: err}
+	}
+	return
+}
+
+// LoadImage opens the image file at path, detects its format from its contents (not its extension), and decodes it.
+// If the file can't be opened, the error from os.Open is returned as is (an *fs.PathError).
+// If the format isn't recognized, it returns an *UnknownImageFormatError, and if decoding fails, it returns an *ImageDecodeError.
+func LoadImage(path string) (img image.Image, format ImageFormat, err error) {
+	fr, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer fr.Close()
+	return decodeImage(fr, path)
+}
+
+// LoadImageFS is like LoadImage, but opens name from fsys, which is handy for images in an embed.FS.
+func LoadImageFS(fsys fs.FS, name string) (img image.Image, format ImageFormat, err error) {
+	fr, err := fsys.Open(name)
+	if err != nil {
+		return
+	}
+	defer fr.Close()
+	return decodeImage(fr, name)
+}
+
+// LoadEImage loads the image file at path with LoadImage, and converts it to an *ebiten.Image with NewEImageFromImage.
+// If mipmaps is true, the *ebiten.Image is created with mipmaps.
+func LoadEImage(path string, mipmaps bool) (eImg *ebiten.Image, err error) {
+	img, _, err := LoadImage(path)
+	if err != nil {
+		return
+	}
+	eImg = NewEImageFromImage(img, mipmaps)
+	return
+}
+
+// LoadEImageFS is like LoadEImage, but opens name from fsys.
+func LoadEImageFS(fsys fs.FS, name string, mipmaps bool) (eImg *ebiten.Image, err error) {
+	img, _, err := LoadImageFS(fsys, name)
+	if err != nil {
+		return
+	}
+	eImg = NewEImageFromImage(img, mipmaps)
+	return
+}
+
+// EncodeImage encodes img to w in the given format. If img is an *ebiten.Image, it is converted with NewImageFromEImage first,
+// since the encoders produce garbage if they're handed an *ebiten.Image directly.
+// If format isn't one we can encode, it returns an *UnknownImageFormatError, and if encoding fails, it returns an *ImageEncodeError.
+func EncodeImage(w io.Writer, img image.Image, format ImageFormat) error {
+	return encodeImage(w, img, format, "")
+}
+
+// encodeImage implements EncodeImage. name is only used in errors.
+func encodeImage(w io.Writer, img image.Image, format ImageFormat, name string) (err error) {
+	if format <= ImageFormatUnknown || format >= NumImageFormats || imageCodecs[format].encode == nil {
+		return &UnknownImageFormatError{Name: name}
+	}
+	if eImg, ok := img.(*ebiten.Image); ok {
+		img = NewImageFromEImage(eImg)
+	}
+	if err = imageCodecs[format].encode(w, img); err != nil {
+		err = &ImageEncodeError{Name: name, Format: format, Err: err}
+	}
+	return
+}
+
+// SaveImage writes img to the file at path, creating or truncating it, in the format matching path's extension
+// (.png, .jpg or .jpeg, .gif, .bmp, .qoi, or .tga). It accepts *ebiten.Images directly.
+// If the extension isn't recognized, it returns an *UnknownImageFormatError without creating the file.
+// If the file can't be created, the error from os.Create is returned as is, and if encoding fails, it returns an *ImageEncodeError.
+func SaveImage(path string, img image.Image) (err error) {
+	format := ImageFormatFromFilename(path)
+	if format == ImageFormatUnknown {
+		return &UnknownImageFormatError{Name: path}
+	}
+	fw, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := fw.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	w := bufio.NewWriter(fw)
+	if err = encodeImage(w, img, format, path); err != nil {
+		return
+	}
+	if err = w.Flush(); err != nil {
+		err = &ImageEncodeError{Name: path, Format: format, Err: err}
+	}
+	return
+}
+
+// EncodePNGPreserveColors converts img to an *image.NRGBA with NewNRGBAFromImage and encodes that as a PNG.
+// png.Encode converts alpha-premultiplied images (including the *image.RGBA returned by NewImageFromEImage) to NRGBA itself, but it does so with
+// color.NRGBAModel, which zeroes the color components wherever alpha is zero. Giving it an *image.NRGBA makes it write the pixel data unchanged,
+// so those colors survive.
+func EncodePNGPreserveColors(w io.Writer, img image.Image) (err error) {
+	if err = png.Encode(w, NewNRGBAFromImage(img)); err != nil {
+		err = &ImageEncodeError{Format: ImageFormatPNG, Err: err}
+	}
+	return
+}
+
+// SavePNGPreserveColors is like SaveImage, but always writes a PNG using EncodePNGPreserveColors, regardless of path's extension.
+func SavePNGPreserveColors(path string, img image.Image) (err error) {
+	fw, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := fw.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	w := bufio.NewWriter(fw)
+	if err = EncodePNGPreserveColors(w, img); err == nil {
+		if err = w.Flush(); err != nil {
+			err = &ImageEncodeError{Format: ImageFormatPNG, Err: err}
+		}
+	}
+	if encodeErr, ok := err.(*ImageEncodeError); ok {
+		encodeErr.Name = path
+	}
+	return
+}
+
+// DecodePNG_NRGBA decodes PNG data from r into an *image.NRGBA.
+// The png package already decodes 8-bit RGBA and gray+alpha PNGs to *image.NRGBA without touching the color components, and those are returned as is.
+// Anything else it returns (paletted, 16-bit, or opaque images) is converted with NewNRGBAFromImage, which uses ToNRGBA rather than color.NRGBAModel,
+// so color components are preserved where alpha is zero.
+// If r doesn't contain PNG data, it returns an *UnknownImageFormatError, and if decoding fails, it returns an *ImageDecodeError.
+func DecodePNG_NRGBA(r io.Reader) (img *image.NRGBA, err error) {
+	return decodePNG_NRGBA(r, "")
+}
+
+// decodePNG_NRGBA implements DecodePNG_NRGBA. name is only used in errors.
+func decodePNG_NRGBA(r io.Reader, name string) (img *image.NRGBA, err error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(headerLen)
+	if DetectImageFormat(header) != ImageFormatPNG {
+		err = &UnknownImageFormatError{Name: name}
+		return
+	}
+	pngImg, err := png.Decode(br)
+	if err != nil {
+		err = &ImageDecodeError{Name: name, Format: ImageFormatPNG, Err: err}
+		return
+	}
+	if nImg, ok := pngImg.(*image.NRGBA); ok {
+		img = nImg
+	} else {
+		img = NewNRGBAFromImage(pngImg)
+	}
+	return
+}
+
+// LoadPNG_NRGBA opens the PNG file at path and decodes it with DecodePNG_NRGBA.
+func LoadPNG_NRGBA(path string) (img *image.NRGBA, err error) {
+	fr, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer fr.Close()
+	return decodePNG_NRGBA(fr, path)
+}
+
+// LoadPNG_NRGBA_FS is like LoadPNG_NRGBA, but opens name from fsys.
+func LoadPNG_NRGBA_FS(fsys fs.FS, name string) (img *image.NRGBA, err error) {
+	fr, err := fsys.Open(name)
+	if err != nil {
+		return
+	}
+	defer fr.Close()
+	return decodePNG_NRGBA(fr, name)
+}
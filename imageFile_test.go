@@ -0,0 +1,165 @@
+package frostutil_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ImageFormatFromFilename(t *testing.T) {
+	ass := assert.New(t)
+	ass.Equal(frostutil.ImageFormatPNG, frostutil.ImageFormatFromFilename("foo/bar.PNG"))
+	ass.Equal(frostutil.ImageFormatJPEG, frostutil.ImageFormatFromFilename("bar.jpg"))
+	ass.Equal(frostutil.ImageFormatJPEG, frostutil.ImageFormatFromFilename("bar.jpeg"))
+	ass.Equal(frostutil.ImageFormatGIF, frostutil.ImageFormatFromFilename("bar.gif"))
+	ass.Equal(frostutil.ImageFormatBMP, frostutil.ImageFormatFromFilename("bar.bmp"))
+	ass.Equal(frostutil.ImageFormatUnknown, frostutil.ImageFormatFromFilename("bar.txt"))
+	ass.Equal(frostutil.ImageFormatUnknown, frostutil.ImageFormatFromFilename("bar"))
+}
+
+// Saves the test image in every format, and checks that loading it back detects the right format and gives an image of the right size.
+// PNG and BMP are lossless, so those should come back with the exact same pixels.
+func Test_SaveAndLoadImage(t *testing.T) {
+	ass := assert.New(t)
+	dir := t.TempDir()
+	img := GetTestImageRGBA(Alpha_FF)
+	for format := frostutil.ImageFormatUnknown + 1; format < frostutil.NumImageFormats; format++ {
+		path := filepath.Join(dir, "test."+format.String())
+		require.NoError(t, frostutil.SaveImage(path, img))
+		loaded, loadedFormat, err := frostutil.LoadImage(path)
+		require.NoError(t, err)
+		ass.Equal(format, loadedFormat)
+		ass.Equal(img.Bounds().Size(), loaded.Bounds().Size())
+		if format == frostutil.ImageFormatPNG || format == frostutil.ImageFormatBMP {
+			cImg := image.NewRGBA(loaded.Bounds())
+			ass.NoError(frostutil.SlowImageCopy(cImg, loaded))
+			ass.NoError(CheckImagePattern(cImg, Alpha_FF), format.String())
+		}
+	}
+}
+
+func Test_LoadImageFS(t *testing.T) {
+	ass := assert.New(t)
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeImage(&buf, GetTestImageNRGBA(Alpha_DiagonalGradient), frostutil.ImageFormatPNG))
+	fsys := fstest.MapFS{
+		"sprites/test.png": &fstest.MapFile{Data: buf.Bytes()},
+		"sprites/text.png": &fstest.MapFile{Data: []byte("this is not an image")},
+		"sprites/bad.png":  &fstest.MapFile{Data: buf.Bytes()[:100]},
+	}
+	img, format, err := frostutil.LoadImageFS(fsys, "sprites/test.png")
+	require.NoError(t, err)
+	ass.Equal(frostutil.ImageFormatPNG, format)
+	ass.NoError(CheckImagePattern(img, Alpha_DiagonalGradient))
+
+	_, _, err = frostutil.LoadImageFS(fsys, "sprites/text.png")
+	var unknownErr *frostutil.UnknownImageFormatError
+	ass.True(errors.As(err, &unknownErr))
+
+	_, _, err = frostutil.LoadImageFS(fsys, "sprites/bad.png")
+	var decodeErr *frostutil.ImageDecodeError
+	if ass.True(errors.As(err, &decodeErr)) {
+		ass.Equal(frostutil.ImageFormatPNG, decodeErr.Format)
+		ass.Equal("sprites/bad.png", decodeErr.Name)
+		ass.True(strings.HasPrefix(err.Error(), "sprites/bad.png: failed to decode "), err.Error())
+	}
+	// without a name, the message doesn't start with an empty one
+	_, _, err = frostutil.DecodeImage(bytes.NewReader(buf.Bytes()[:100]))
+	if ass.True(errors.As(err, &decodeErr)) {
+		ass.Empty(decodeErr.Name)
+		ass.True(strings.HasPrefix(err.Error(), "failed to decode "), err.Error())
+	}
+
+	_, _, err = frostutil.LoadImageFS(fsys, "sprites/missing.png")
+	ass.True(errors.Is(err, os.ErrNotExist))
+}
+
+func Test_SaveImageUnknownExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+	err := frostutil.SaveImage(path, GetTestImageRGBA(Alpha_FF))
+	var unknownErr *frostutil.UnknownImageFormatError
+	assert.True(t, errors.As(err, &unknownErr))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// Tests SaveImage with an *ebiten.Image, and LoadEImage.
+func Test_SaveAndLoadEImage(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_SaveAndLoadEImage)
+}
+
+func test_SaveAndLoadEImage(t *testing.T) {
+	ass := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.png")
+	eImg := frostutil.NewEImageFromImage(GetTestImageRGBA(Alpha_FF), false)
+	require.NoError(t, frostutil.SaveImage(path, eImg))
+	loaded, err := frostutil.LoadEImage(path, false)
+	require.NoError(t, err)
+	ass.NoError(CheckImagePattern(loaded, Alpha_FF))
+}
+
+// Checks that color components in pixels with zero alpha survive being saved with SavePNGPreserveColors and loaded with LoadPNG_NRGBA.
+func Test_PNGPreserveColors(t *testing.T) {
+	ass := assert.New(t)
+	dir := t.TempDir()
+	for alphaTestMode := AlphaTestMode(0); alphaTestMode < NumAlphaTestModes; alphaTestMode++ {
+		path := filepath.Join(dir, "nrgba.png")
+		require.NoError(t, frostutil.SavePNGPreserveColors(path, GetTestImageNRGBA(alphaTestMode)))
+		loaded, err := frostutil.LoadPNG_NRGBA(path)
+		require.NoError(t, err)
+		ass.NoError(CheckImagePattern(loaded, alphaTestMode))
+	}
+	// png.Encode can't encode an empty image, and the error has the path in it
+	path := filepath.Join(dir, "empty.png")
+	err := frostutil.SavePNGPreserveColors(path, image.NewRGBA(image.Rect(0, 0, 0, 0)))
+	var encodeErr *frostutil.ImageEncodeError
+	if ass.True(errors.As(err, &encodeErr)) {
+		ass.Equal(path, encodeErr.Name)
+		ass.Equal(frostutil.ImageFormatPNG, encodeErr.Format)
+	}
+
+	// An *image.RGBA whose pixel buffer has color without alpha, like you'd get from MultiplyAlphaBytesPreserveColors.
+	nImg := GetTestImageNRGBA(Alpha_00).(*image.NRGBA)
+	rImg := image.NewRGBA(nImg.Bounds())
+	for i := 0; i < len(nImg.Pix); i += 4 {
+		rImg.Pix[i], rImg.Pix[i+1], rImg.Pix[i+2], rImg.Pix[i+3] = frostutil.MultiplyAlphaBytesPreserveColors(nImg.Pix[i], nImg.Pix[i+1], nImg.Pix[i+2], nImg.Pix[i+3])
+	}
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodePNGPreserveColors(&buf, rImg))
+	loaded, err := frostutil.DecodePNG_NRGBA(&buf)
+	require.NoError(t, err)
+	ass.NoError(CheckImagePattern(loaded, Alpha_00))
+
+	_, err = frostutil.DecodePNG_NRGBA(bytes.NewReader([]byte("GIF89a")))
+	var unknownErr *frostutil.UnknownImageFormatError
+	ass.True(errors.As(err, &unknownErr))
+}
+
+// Checks that NewNRGBAFromImage converts opaque and paletted images, and images whose bounds don't start at (0, 0).
+func Test_NewNRGBAFromImage(t *testing.T) {
+	ass := assert.New(t)
+	img := GetTestImageRGBA(Alpha_FF).(*image.RGBA)
+	ass.NoError(CheckImagePattern(frostutil.NewNRGBAFromImage(img), Alpha_FF))
+
+	sub := img.SubImage(image.Rect(10, 20, 30, 40))
+	nImg := frostutil.NewNRGBAFromImage(sub)
+	ass.Equal(image.Rect(0, 0, 20, 20), nImg.Bounds())
+	ass.Equal(img.RGBAAt(15, 25).R, nImg.NRGBAAt(5, 5).R)
+	ass.Equal(img.RGBAAt(15, 25).G, nImg.NRGBAAt(5, 5).G)
+
+	pal := image.NewPaletted(image.Rect(0, 0, 2, 1), color.Palette{color.NRGBA{R: 10, G: 20, B: 30, A: 0}, color.NRGBA{R: 40, G: 50, B: 60, A: 255}})
+	pal.SetColorIndex(1, 0, 1)
+	nImg = frostutil.NewNRGBAFromImage(pal)
+	ass.Equal(color.NRGBA{R: 10, G: 20, B: 30, A: 0}, nImg.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{R: 40, G: 50, B: 60, A: 255}, nImg.NRGBAAt(1, 0))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"testing"
 
 	"github.com/amanitaverna/frostutil"
@@ -323,3 +324,43 @@ func test_SlowImageCopyImpl(ass *assert.Assertions, img image.Image, alphaTestMo
 		ass.Nil(err)
 	}
 }
+
+// Tests that SlowImageCopy preserves color components at zero alpha when writing to non-premultiplied destinations,
+// and that it can write to draw.Images that it doesn't have a fast path for.
+func Test_SlowImageCopyPreservesColors(t *testing.T) {
+	ass := assert.New(t)
+	for alphaTestMode := AlphaTestMode(0); alphaTestMode < NumAlphaTestModes; alphaTestMode++ {
+		img := GetTestImageNRGBA(alphaTestMode)
+		// wrap the image so that SlowImageCopy can't recognize the source type
+		src := image.NewNRGBA(img.Bounds())
+		ass.NoError(frostutil.SlowImageCopy(src, struct{ image.Image }{img}))
+		if err := CheckImagePattern(src, alphaTestMode); err != nil {
+			ass.Fail(fmt.Sprintf("SlowImageCopy failed to preserve colors when copying to an *image.NRGBA with alphaTestMode=%v", alphaTestMode), err.Error())
+		}
+
+		dst := image.NewNRGBA64(img.Bounds())
+		ass.NoError(frostutil.SlowImageCopy(dst, img))
+		out := image.NewNRGBA(img.Bounds())
+		ass.NoError(frostutil.SlowImageCopy(out, dst))
+		if err := CheckImagePattern(out, alphaTestMode); err != nil {
+			ass.Fail(fmt.Sprintf("SlowImageCopy failed to preserve colors when copying through an *image.NRGBA64 with alphaTestMode=%v", alphaTestMode), err.Error())
+		}
+	}
+	ass.Error(frostutil.SlowImageCopy(nil, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+
+	// 16-bit sources keep all 16 bits when they're copied to a 16-bit destination
+	src64 := image.NewNRGBA64(image.Rect(0, 0, 3, 1))
+	src64.SetNRGBA64(0, 0, color.NRGBA64{0x1234, 0x5678, 0x9abc, 0x8001})
+	src64.SetNRGBA64(1, 0, color.NRGBA64{0x1234, 0x5678, 0x9abc, 0})
+	src64.SetNRGBA64(2, 0, color.NRGBA64{0xfedc, 0x0102, 0x0304, 0xffff})
+	dst64 := image.NewNRGBA64(src64.Bounds())
+	ass.NoError(frostutil.SlowImageCopy(dst64, struct{ image.Image }{src64}))
+	ass.Equal(src64.Pix, dst64.Pix)
+	rgba64 := image.NewRGBA64(image.Rect(0, 0, 2, 1))
+	rgba64.SetRGBA64(0, 0, color.RGBA64{0x1234, 0x2345, 0x3456, 0x8001})
+	rgba64.SetRGBA64(1, 0, color.RGBA64{0x1234, 0x2345, 0x3456, 0})
+	dst64 = image.NewNRGBA64(rgba64.Bounds())
+	ass.NoError(frostutil.SlowImageCopy(dst64, rgba64))
+	ass.Equal(color.NRGBA64Model.Convert(rgba64.At(0, 0)), dst64.At(0, 0))
+	ass.Equal(color.NRGBA64{0x1234, 0x2345, 0x3456, 0}, dst64.At(1, 0))
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"image"
-	"image/png"
 	"os"
 	"strings"
 	"testing"
@@ -18,16 +17,28 @@ const (
 	expectedFolder string = "testdata/expected"
 	failedFolder   string = "testdata/failed"
 	pngStr         string = ".png"
+	qoiStr         string = ".qoi"
 )
 
 // MatchesImage compares an image.Image to "testdata/expected/<imageName>.png". If img is not nil, it attempts to open "testdata/expected/<imageName>.png".
+// If that doesn't exist but "testdata/expected/<imageName>.qoi" does, it uses that instead, since QOI images are much faster to decode.
 // If it succeeds, it converts it to an image.Image, and then compares the two images.
-// If it fails, it writes the image to "testdata/failed/<imageName>.png" and raises a test failure.
-// It can handle *ebiten.Images and save them as PNGs.
+// If it fails, it writes the image to "testdata/failed/<imageName>.png" (or .qoi, if the expected image was a QOI) and raises a test failure.
+// It can handle *ebiten.Images (which are read with a single ReadPixels call, into an EImageSnapshot) and save them as PNGs. Failed images are saved with EncodePNGPreserveColors or EncodeQOI.
 // Also returns true if the images match, and false if they don't.
 func MatchesImage(t *testing.T, imageName string, img image.Image) bool {
 	if assert.NotNil(t, img) {
-		filename := expectedFolder + "/" + imageName + pngStr
+		if eImg, ok := img.(*ebiten.Image); ok {
+			// reading it once is much faster than calling At on every pixel
+			img = NewEImageSnapshot(eImg)
+		}
+		ext := pngStr
+		if _, err := os.Stat(expectedFolder + "/" + imageName + pngStr); os.IsNotExist(err) {
+			if _, err := os.Stat(expectedFolder + "/" + imageName + qoiStr); err == nil {
+				ext = qoiStr
+			}
+		}
+		filename := expectedFolder + "/" + imageName + ext
 		fr, err := os.Open(filename)
 		failedBuilder := &strings.Builder{}
 		if err != nil {
@@ -41,17 +52,17 @@ func MatchesImage(t *testing.T, imageName string, img image.Image) bool {
 			require.NotNil(t, fr)
 			defer fr.Close()
 			r := bufio.NewReader(fr)
-			pngImg, err := png.Decode(r)
+			expectedImg, _, err := DecodeImage(r)
 			require.NoError(t, err)
-			require.NotNil(t, pngImg)
+			require.NotNil(t, expectedImg)
 			bounds := img.Bounds()
-			if pngImg.Bounds().Dx() != bounds.Dx() || pngImg.Bounds().Dy() != bounds.Dy() {
-				failedBuilder.WriteString(fmt.Sprintf("Dimensions of %v (%v, %v) don't match. Expected (%v, %v).\n", imageName, bounds.Dx(), bounds.Dy(), pngImg.Bounds().Dx(), pngImg.Bounds().Dy()))
+			if expectedImg.Bounds().Dx() != bounds.Dx() || expectedImg.Bounds().Dy() != bounds.Dy() {
+				failedBuilder.WriteString(fmt.Sprintf("Dimensions of %v (%v, %v) don't match. Expected (%v, %v).\n", imageName, bounds.Dx(), bounds.Dy(), expectedImg.Bounds().Dx(), expectedImg.Bounds().Dy()))
 			} else {
 				for y := 0; y < bounds.Dy() && failedBuilder.Len() < 1000; y++ {
 					for x := 0; x < bounds.Dx() && failedBuilder.Len() < 1000; x++ {
 						c1 := img.At(x+bounds.Min.X, y+bounds.Min.Y)
-						c2 := pngImg.At(x+pngImg.Bounds().Min.X, y+pngImg.Bounds().Min.Y)
+						c2 := expectedImg.At(x+expectedImg.Bounds().Min.X, y+expectedImg.Bounds().Min.Y)
 						r1, g1, b1, a1 := ToNRGBA(c1)
 						r2, g2, b2, a2 := ToNRGBA(c2)
 						if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
@@ -72,17 +83,18 @@ func MatchesImage(t *testing.T, imageName string, img image.Image) bool {
 		}
 		failed := failedBuilder.String()
 		if len(failed) > 0 {
-			failedFilename := failedFolder + "/" + imageName + pngStr
+			failedFilename := failedFolder + "/" + imageName + ext
 			os.MkdirAll(failedFolder, 0644) //read and write permissions for the owner, read-only for group and others
 			fw, err := os.Create(failedFilename)
 			require.NoError(t, err)
 			defer fw.Close()
 			w := bufio.NewWriter(fw)
-			eImg, isEImg := img.(*ebiten.Image)
-			if isEImg {
-				png.Encode(w, NewImageFromEImage(eImg))
+			// We compare colors with ToNRGBA, which keeps the color components of pixels with zero alpha, so we need to save them too,
+			// or moving the failed image into the expected folder wouldn't make the test pass.
+			if ext == qoiStr {
+				EncodeQOI(w, img)
 			} else {
-				png.Encode(w, img)
+				EncodePNGPreserveColors(w, img)
 			}
 			w.Flush()
 			assert.Fail(t, failed)
@@ -0,0 +1,116 @@
+package frostutil
+
+import (
+	"image"
+	"math"
+)
+
+// GradientOperator selects the kernel NewNormalMap uses to measure slopes.
+type GradientOperator int
+
+const (
+	GradientSobel  GradientOperator = iota // The Sobel operator, a 3x3 kernel that weights the center row or column twice as much as the others.
+	GradientScharr                         // The Scharr operator, which is like Sobel but more accurate for diagonal slopes.
+	NumGradientOperators
+)
+
+// EdgeMode says how pixels past the edges of an image are treated.
+type EdgeMode int
+
+const (
+	EdgeClamp EdgeMode = iota // Pixels past the edges repeat the edge pixels.
+	EdgeWrap                  // Pixels past the edges come from the opposite edge, for tileable textures.
+	NumEdgeModes
+)
+
+// NormalMapOptions controls NewNormalMap.
+type NormalMapOptions struct {
+	Operator GradientOperator
+	Strength float64 // How steep the slopes are. Heights go from 0 to 1, and the gradients are multiplied by this. If it's <= 0, 1 is used.
+	Edges    EdgeMode
+	// By default the normal map follows the OpenGL convention, with green pointing up. If InvertY is true, green points down instead, like DirectX.
+	InvertY bool
+}
+
+// gradientKernels holds the weights of each operator's x kernel for the rows above, at, and below the pixel, normalized so a slope of 1 per pixel gives 1.
+// The y kernels are the same, transposed.
+var gradientKernels = [NumGradientOperators][3]float64{
+	GradientSobel:  {1.0 / 8, 2.0 / 8, 1.0 / 8},
+	GradientScharr: {3.0 / 32, 10.0 / 32, 3.0 / 32},
+}
+
+// NewNormalMap generates a tangent-space normal map from a height image, as an opaque *image.NRGBA with the same size (and its top-left corner at (0, 0)).
+// *image.Gray and *image.Gray16 images are used as heights directly. For anything else, the height is the luminance of the alpha-premultiplied color
+// (so transparent pixels are at the bottom, and sprites' silhouettes get edges); *ebiten.Images are read with NewNRGBAFromImage.
+// Each normal's x, y, and z components, from -1 to 1, are stored in red, green, and blue, from 0 to 255, so flat areas are (128, 128, 255).
+func NewNormalMap(img image.Image, opts NormalMapOptions) *image.NRGBA {
+	heights, width, height := heightValues(img)
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	strength := opts.Strength
+	if strength <= 0 {
+		strength = 1
+	}
+	kernel := gradientKernels[GradientSobel]
+	if opts.Operator >= 0 && opts.Operator < NumGradientOperators {
+		kernel = gradientKernels[opts.Operator]
+	}
+	at := func(x, y int) float64 {
+		if opts.Edges == EdgeWrap {
+			x, y = (x%width+width)%width, (y%height+height)%height
+		} else {
+			x, y = Min(Max(x, 0), width-1), Min(Max(y, 0), height-1)
+		}
+		return heights[y*width+x]
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy float64
+			for i, w := range kernel {
+				dx += w * (at(x+1, y+i-1) - at(x-1, y+i-1))
+				dy += w * (at(x+i-1, y+1) - at(x+i-1, y-1))
+			}
+			// dy is the slope going down the image, so the normal's y (pointing up) is +dy
+			nx, ny, nz := -dx*strength, dy*strength, 1.0
+			if opts.InvertY {
+				ny = -ny
+			}
+			length := math.Sqrt(nx*nx + ny*ny + nz*nz)
+			idx := y*out.Stride + x*4
+			out.Pix[idx] = byte((nx/length*0.5+0.5)*0xff + 0.5)
+			out.Pix[idx+1] = byte((ny/length*0.5+0.5)*0xff + 0.5)
+			out.Pix[idx+2] = byte((nz/length*0.5+0.5)*0xff + 0.5)
+			out.Pix[idx+3] = 0xff
+		}
+	}
+	return out
+}
+
+// heightValues returns img's heights from 0 to 1, row by row with no padding, as described in NewNormalMap.
+func heightValues(img image.Image) (heights []float64, width, height int) {
+	bounds := img.Bounds()
+	width, height = bounds.Dx(), bounds.Dy()
+	heights = make([]float64, width*height)
+	switch xImg := img.(type) {
+	case *image.Gray:
+		for y := 0; y < height; y++ {
+			idx := xImg.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			for x := 0; x < width; x++ {
+				heights[y*width+x] = float64(xImg.Pix[idx+x]) / 0xff
+			}
+		}
+	case *image.Gray16:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				heights[y*width+x] = float64(xImg.Gray16At(bounds.Min.X+x, bounds.Min.Y+y).Y) / 0xffff
+			}
+		}
+	default:
+		nrgba := NewNRGBAFromImage(img)
+		for i := range heights {
+			p := nrgba.Pix[i*4 : i*4+4]
+			luma := 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+			heights[i] = luma * float64(p[3]) / (0xff * 0xff)
+		}
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewNormalMap(t *testing.T) {
+	ass := assert.New(t)
+
+	flat := image.NewGray(image.Rect(0, 0, 4, 4))
+	for i := range flat.Pix {
+		flat.Pix[i] = 77
+	}
+	normals := frostutil.NewNormalMap(flat, frostutil.NormalMapOptions{})
+	ass.Equal(image.Rect(0, 0, 4, 4), normals.Bounds())
+	ass.Equal(color.NRGBA{128, 128, 255, 255}, normals.NRGBAAt(2, 2))
+
+	// a ramp going up to the right, steep enough with this strength to tilt the normals 45 degrees to the left
+	ramp := image.NewGray(image.Rect(0, 0, 6, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			ramp.SetGray(x, y, color.Gray{Y: byte(x * 10)})
+		}
+	}
+	for _, op := range []frostutil.GradientOperator{frostutil.GradientSobel, frostutil.GradientScharr} {
+		normals = frostutil.NewNormalMap(ramp, frostutil.NormalMapOptions{Operator: op, Strength: 25.5})
+		ass.Equal(color.NRGBA{37, 128, 218, 255}, normals.NRGBAAt(2, 3), "operator %v", op)
+	}
+	// it's half as steep at the clamped edges
+	ass.Greater(normals.NRGBAAt(0, 3).R, normals.NRGBAAt(2, 3).R)
+
+	// a ramp going up towards the bottom faces up, with green above 128, unless Y is inverted
+	ramp = image.NewGray(image.Rect(0, 0, 6, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			ramp.SetGray(x, y, color.Gray{Y: byte(y * 10)})
+		}
+	}
+	normals = frostutil.NewNormalMap(ramp, frostutil.NormalMapOptions{Strength: 25.5})
+	ass.Equal(color.NRGBA{128, 218, 218, 255}, normals.NRGBAAt(2, 2))
+	normals = frostutil.NewNormalMap(ramp, frostutil.NormalMapOptions{Strength: 25.5, InvertY: true})
+	ass.Equal(color.NRGBA{128, 37, 218, 255}, normals.NRGBAAt(2, 2))
+
+	// with wrapping, a tileable texture gives a tileable normal map: shifting the texture shifts the normals
+	tile := image.NewGray(image.Rect(0, 0, 8, 8))
+	shifted := image.NewGray(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			v := byte(128 + 100*math.Sin(float64(x)*math.Pi/4)*math.Cos(float64(y)*math.Pi/4))
+			tile.SetGray(x, y, color.Gray{Y: v})
+			shifted.SetGray((x+3)%8, (y+5)%8, color.Gray{Y: v})
+		}
+	}
+	opts := frostutil.NormalMapOptions{Edges: frostutil.EdgeWrap, Strength: 4}
+	normals = frostutil.NewNormalMap(tile, opts)
+	shiftedNormals := frostutil.NewNormalMap(shifted, opts)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			ass.Equal(normals.NRGBAAt(x, y), shiftedNormals.NRGBAAt((x+3)%8, (y+5)%8), "(%v, %v)", x, y)
+		}
+	}
+	clamped := frostutil.NewNormalMap(tile, frostutil.NormalMapOptions{Strength: 4})
+	ass.NotEqual(normals.NRGBAAt(0, 0), clamped.NRGBAAt(0, 0))
+	ass.Equal(normals.NRGBAAt(3, 3), clamped.NRGBAAt(3, 3))
+
+	// color images use their premultiplied luminance, so a sprite's edges slope down to the transparent pixels around it
+	sprite := makeFilterSprite()
+	normals = frostutil.NewNormalMap(sprite, frostutil.NormalMapOptions{})
+	ass.Less(normals.NRGBAAt(3, 5).R, uint8(128))
+	ass.Greater(normals.NRGBAAt(6, 5).R, uint8(128))
+	ass.Equal(color.NRGBA{128, 128, 255, 255}, normals.NRGBAAt(0, 0))
+}
@@ -0,0 +1,214 @@
+package frostutil
+
+import (
+	"image"
+	"image/color"
+)
+
+// PixelFormat describes how a pixel is stored in a packed pixel buffer, like the framebuffer of an embedded display or an emulator.
+// Pack and Unpack convert between a pixel's BytesPerPixel bytes and a straight (not alpha-premultiplied) color. Formats without an alpha
+// channel drop it when packing, keeping the straight color components, and unpack to opaque colors.
+// Use NewPixelFormat to make your own, or one of the predefined formats.
+type PixelFormat struct {
+	Name          string
+	BytesPerPixel int
+	Pack          func(p []byte, c color.NRGBA) // Writes c into p, which is BytesPerPixel bytes long.
+	Unpack        func(p []byte) color.NRGBA    // Reads the color from p, which is BytesPerPixel bytes long.
+	model         color.Model
+}
+
+// NewPixelFormat creates a PixelFormat with the given name, size, and conversion functions.
+func NewPixelFormat(name string, bytesPerPixel int, pack func(p []byte, c color.NRGBA), unpack func(p []byte) color.NRGBA) (f *PixelFormat) {
+	f = &PixelFormat{Name: name, BytesPerPixel: bytesPerPixel, Pack: pack, Unpack: unpack}
+	f.model = color.ModelFunc(func(c color.Color) color.Color {
+		p := make([]byte, f.BytesPerPixel)
+		f.Pack(p, ToNRGBA_Color(c).(color.NRGBA))
+		return f.Unpack(p)
+	})
+	return
+}
+
+// Model returns a color.Model which converts colors to the closest color the format can store, as a color.NRGBA.
+func (f *PixelFormat) Model() color.Model {
+	return f.model
+}
+
+// String returns the format's name.
+func (f *PixelFormat) String() string {
+	return f.Name
+}
+
+// The predefined pixel formats. Multi-byte values are little-endian, and components narrower than 8 bits are rounded to the nearest value
+// when packed, and scaled back up to the full 0-255 range (by repeating their bits) when unpacked.
+var (
+	// PixelFormatRGB565 stores each pixel in a little-endian uint16, with 5 bits of red in the top bits, then 6 of green, then 5 of blue.
+	PixelFormatRGB565 = NewPixelFormat("RGB565", 2, func(p []byte, c color.NRGBA) {
+		v := uint16(narrowComponent(c.R, 5))<<11 | uint16(narrowComponent(c.G, 6))<<5 | uint16(narrowComponent(c.B, 5))
+		p[0], p[1] = byte(v), byte(v>>8)
+	}, func(p []byte) color.NRGBA {
+		v := uint16(p[0]) | uint16(p[1])<<8
+		return color.NRGBA{widenComponent(byte(v>>11), 5), widenComponent(byte(v>>5)&0x3f, 6), widenComponent(byte(v)&0x1f, 5), 0xff}
+	})
+	// PixelFormatRGBA4444 stores each pixel in a little-endian uint16, with 4 bits each of red (in the top bits), green, blue, and alpha.
+	PixelFormatRGBA4444 = NewPixelFormat("RGBA4444", 2, func(p []byte, c color.NRGBA) {
+		v := uint16(narrowComponent(c.R, 4))<<12 | uint16(narrowComponent(c.G, 4))<<8 | uint16(narrowComponent(c.B, 4))<<4 | uint16(narrowComponent(c.A, 4))
+		p[0], p[1] = byte(v), byte(v>>8)
+	}, func(p []byte) color.NRGBA {
+		return color.NRGBA{widenComponent(p[1]>>4, 4), widenComponent(p[1]&0xf, 4), widenComponent(p[0]>>4, 4), widenComponent(p[0]&0xf, 4)}
+	})
+	// PixelFormatGray8 stores each pixel's luma in one byte, weighted like color.GrayModel.
+	PixelFormatGray8 = NewPixelFormat("Gray8", 1, func(p []byte, c color.NRGBA) {
+		p[0] = byte((19595*uint32(c.R) + 38470*uint32(c.G) + 7471*uint32(c.B) + 1<<15) >> 16)
+	}, func(p []byte) color.NRGBA {
+		return color.NRGBA{p[0], p[0], p[0], 0xff}
+	})
+	// PixelFormatBGRA8 stores each pixel in four bytes, in blue, green, red, alpha order, with straight alpha.
+	PixelFormatBGRA8 = NewPixelFormat("BGRA8", 4, func(p []byte, c color.NRGBA) {
+		p[0], p[1], p[2], p[3] = c.B, c.G, c.R, c.A
+	}, func(p []byte) color.NRGBA {
+		return color.NRGBA{p[2], p[1], p[0], p[3]}
+	})
+	// PixelFormatRGB8 stores each pixel in three bytes, in red, green, blue order.
+	PixelFormatRGB8 = NewPixelFormat("RGB8", 3, func(p []byte, c color.NRGBA) {
+		p[0], p[1], p[2] = c.R, c.G, c.B
+	}, func(p []byte) color.NRGBA {
+		return color.NRGBA{p[0], p[1], p[2], 0xff}
+	})
+)
+
+// narrowComponent rounds an 8-bit color component to the nearest value with the given number of bits.
+func narrowComponent(v byte, bits uint) byte {
+	maxV := uint32(1)<<bits - 1
+	return byte((uint32(v)*maxV + 0x7f) / 0xff)
+}
+
+// widenComponent scales a color component with the given number of bits up to 8 bits, by repeating its bits, so the largest value becomes 0xff.
+func widenComponent(v byte, bits uint) (out byte) {
+	for shift := int(8 - bits); shift > -int(bits); shift -= int(bits) {
+		if shift >= 0 {
+			out |= v << shift
+		} else {
+			out |= v >> -shift
+		}
+	}
+	return
+}
+
+// PixelBuffer is an image.Image and draw.Image whose pixels are stored in a PixelFormat. Like *image.RGBA, the pixel at (x, y) starts at
+// Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*Format.BytesPerPixel]. At returns color.NRGBAs, and Set converts colors with ToNRGBA, so the colors
+// of transparent pixels are kept (in formats with an alpha channel).
+type PixelBuffer struct {
+	Pix    []byte
+	Stride int
+	Rect   image.Rectangle
+	Format *PixelFormat
+}
+
+// NewPixelBuffer creates a PixelBuffer with the given bounds and format, which is transparent black (or whatever all zero bytes are in the format).
+func NewPixelBuffer(r image.Rectangle, format *PixelFormat) *PixelBuffer {
+	stride := r.Dx() * format.BytesPerPixel
+	return &PixelBuffer{Pix: make([]byte, stride*r.Dy()), Stride: stride, Rect: r, Format: format}
+}
+
+// NewPixelBufferFromImage converts img to a new PixelBuffer in the given format, with its top-left corner at (0, 0).
+// img is read with NewNRGBAFromImage, so *image.RGBA, *image.NRGBA, and *ebiten.Images are read straight from their pixels.
+func NewPixelBufferFromImage(img image.Image, format *PixelFormat) (pb *PixelBuffer) {
+	nImg := NewNRGBAFromImage(img)
+	pb = NewPixelBuffer(nImg.Rect, format)
+	width, height := nImg.Rect.Dx(), nImg.Rect.Dy()
+	for y := 0; y < height; y++ {
+		iIdx := y * nImg.Stride
+		oIdx := y * pb.Stride
+		for x := 0; x < width; x++ {
+			p := nImg.Pix[iIdx : iIdx+4 : iIdx+4]
+			format.Pack(pb.Pix[oIdx:oIdx+format.BytesPerPixel], color.NRGBA{p[0], p[1], p[2], p[3]})
+			iIdx += 4
+			oIdx += format.BytesPerPixel
+		}
+	}
+	return
+}
+
+// ColorModel returns the format's Model.
+func (pb *PixelBuffer) ColorModel() color.Model {
+	return pb.Format.Model()
+}
+
+// Bounds returns the buffer's bounds.
+func (pb *PixelBuffer) Bounds() image.Rectangle {
+	return pb.Rect
+}
+
+// PixOffset returns the index of the first byte of the pixel at (x, y) in Pix.
+func (pb *PixelBuffer) PixOffset(x, y int) int {
+	return (y-pb.Rect.Min.Y)*pb.Stride + (x-pb.Rect.Min.X)*pb.Format.BytesPerPixel
+}
+
+// At returns the color of the pixel at (x, y) as a color.NRGBA.
+func (pb *PixelBuffer) At(x, y int) color.Color {
+	return pb.NRGBAAt(x, y)
+}
+
+// NRGBAAt returns the color of the pixel at (x, y), or transparent black if it's outside the bounds.
+func (pb *PixelBuffer) NRGBAAt(x, y int) color.NRGBA {
+	if !(image.Point{x, y}.In(pb.Rect)) {
+		return color.NRGBA{}
+	}
+	idx := pb.PixOffset(x, y)
+	return pb.Format.Unpack(pb.Pix[idx : idx+pb.Format.BytesPerPixel])
+}
+
+// Set sets the pixel at (x, y) to c, converted with ToNRGBA and packed into the format. Pixels outside the bounds are ignored.
+func (pb *PixelBuffer) Set(x, y int, c color.Color) {
+	r, g, b, a := ToNRGBA(c)
+	pb.SetNRGBA(x, y, color.NRGBA{r, g, b, a})
+}
+
+// SetNRGBA sets the pixel at (x, y) to c, packed into the format. Pixels outside the bounds are ignored.
+func (pb *PixelBuffer) SetNRGBA(x, y int, c color.NRGBA) {
+	if !(image.Point{x, y}.In(pb.Rect)) {
+		return
+	}
+	idx := pb.PixOffset(x, y)
+	pb.Format.Pack(pb.Pix[idx:idx+pb.Format.BytesPerPixel], c)
+}
+
+// SubImage returns a PixelBuffer of the part of the buffer inside r, which shares its pixels.
+func (pb *PixelBuffer) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(pb.Rect)
+	if r.Empty() {
+		return &PixelBuffer{Format: pb.Format}
+	}
+	idx := pb.PixOffset(r.Min.X, r.Min.Y)
+	return &PixelBuffer{Pix: pb.Pix[idx:], Stride: pb.Stride, Rect: r, Format: pb.Format}
+}
+
+// NRGBAImage unpacks the buffer's pixels into a new *image.NRGBA with the same bounds.
+func (pb *PixelBuffer) NRGBAImage() (img *image.NRGBA) {
+	img = image.NewNRGBA(pb.Rect)
+	width, height := pb.Rect.Dx(), pb.Rect.Dy()
+	bpp := pb.Format.BytesPerPixel
+	for y := 0; y < height; y++ {
+		iIdx := y * pb.Stride
+		oIdx := y * img.Stride
+		for x := 0; x < width; x++ {
+			c := pb.Format.Unpack(pb.Pix[iIdx : iIdx+bpp])
+			img.Pix[oIdx], img.Pix[oIdx+1], img.Pix[oIdx+2], img.Pix[oIdx+3] = c.R, c.G, c.B, c.A
+			iIdx += bpp
+			oIdx += 4
+		}
+	}
+	return
+}
+
+// RGBAImage unpacks the buffer's pixels into a new *image.RGBA with the same bounds, premultiplying them with MultiplyAlphaBytesPreserveColors,
+// so the colors of transparent pixels are kept, like RGBAView and SlowImageCopy do. That gives different results from color.RGBAModel
+// (and NewEImageFromImage), which store fully transparent pixels as transparent black.
+func (pb *PixelBuffer) RGBAImage() (img *image.RGBA) {
+	nImg := pb.NRGBAImage()
+	img = &image.RGBA{Pix: nImg.Pix, Stride: nImg.Stride, Rect: nImg.Rect}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = MultiplyAlphaBytesPreserveColors(img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3])
+	}
+	return
+}
@@ -0,0 +1,115 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPixelFormats = []*frostutil.PixelFormat{
+	frostutil.PixelFormatRGB565, frostutil.PixelFormatRGBA4444, frostutil.PixelFormatGray8, frostutil.PixelFormatBGRA8, frostutil.PixelFormatRGB8,
+}
+
+func Test_PixelFormatLayouts(t *testing.T) {
+	ass := assert.New(t)
+	pack := func(f *frostutil.PixelFormat, c color.NRGBA) []byte {
+		p := make([]byte, f.BytesPerPixel)
+		f.Pack(p, c)
+		return p
+	}
+	ass.Equal([]byte{0x00, 0xf8}, pack(frostutil.PixelFormatRGB565, color.NRGBA{0xff, 0, 0, 0xff}))
+	ass.Equal([]byte{0xe0, 0x07}, pack(frostutil.PixelFormatRGB565, color.NRGBA{0, 0xff, 0, 0xff}))
+	ass.Equal([]byte{0x1f, 0x00}, pack(frostutil.PixelFormatRGB565, color.NRGBA{0, 0, 0xff, 0x80}))
+	ass.Equal([]byte{0x3c, 0xf0}, pack(frostutil.PixelFormatRGBA4444, color.NRGBA{0xff, 0, 0x33, 0xcc}))
+	ass.Equal([]byte{0x4c}, pack(frostutil.PixelFormatGray8, color.NRGBA{0xff, 0, 0, 0xff}))
+	ass.Equal([]byte{3, 2, 1, 4}, pack(frostutil.PixelFormatBGRA8, color.NRGBA{1, 2, 3, 4}))
+	ass.Equal([]byte{1, 2, 3}, pack(frostutil.PixelFormatRGB8, color.NRGBA{1, 2, 3, 4}))
+
+	ass.Equal(color.NRGBA{0xff, 0xff, 0xff, 0xff}, frostutil.PixelFormatRGB565.Unpack([]byte{0xff, 0xff}))
+	ass.Equal(color.NRGBA{0x84, 0x82, 0x84, 0xff}, frostutil.PixelFormatRGB565.Unpack([]byte{0x10, 0x84}))
+	ass.Equal(color.NRGBA{0xff, 0, 0x33, 0xcc}, frostutil.PixelFormatRGBA4444.Unpack([]byte{0x3c, 0xf0}))
+	ass.Equal(color.NRGBA{0x4c, 0x4c, 0x4c, 0xff}, frostutil.PixelFormatGray8.Unpack([]byte{0x4c}))
+	ass.Equal(color.NRGBA{3, 2, 1, 4}, frostutil.PixelFormatBGRA8.Unpack([]byte{1, 2, 3, 4}))
+	ass.Equal("RGB565", frostutil.PixelFormatRGB565.String())
+}
+
+func Test_PixelFormatRoundTrip(t *testing.T) {
+	ass := assert.New(t)
+	for _, f := range testPixelFormats {
+		// every color a format unpacks to packs back to the same bytes
+		p := make([]byte, f.BytesPerPixel)
+		for i := 0; i < 0x10000; i += 7 {
+			for j := range p {
+				p[j] = byte(i >> (8 * (j & 1)))
+			}
+			c := f.Unpack(p)
+			q := make([]byte, f.BytesPerPixel)
+			f.Pack(q, c)
+			if !ass.Equal(c, f.Unpack(q), "%v %x", f, p) {
+				break
+			}
+			ass.Equal(c, f.Model().Convert(c), "%v", f)
+		}
+	}
+}
+
+func Test_PixelBuffer(t *testing.T) {
+	ass := assert.New(t)
+	src := GetTestImageNRGBA(Alpha_DiagonalGradient).(*image.NRGBA)
+	for _, f := range testPixelFormats {
+		pb := frostutil.NewPixelBufferFromImage(src, f)
+		ass.Equal(src.Bounds(), pb.Bounds())
+		ass.Equal(testImgWidth*f.BytesPerPixel, pb.Stride)
+		nImg := pb.NRGBAImage()
+		rImg := pb.RGBAImage()
+		for y := 0; y < testImgHeight; y += 3 {
+			for x := 0; x < testImgWidth; x += 5 {
+				expected := f.Model().Convert(src.NRGBAAt(x, y)).(color.NRGBA)
+				ass.Equal(expected, pb.At(x, y), "%v (%v, %v)", f, x, y)
+				ass.Equal(expected, nImg.NRGBAAt(x, y), "%v (%v, %v)", f, x, y)
+				var premultiplied color.RGBA
+				premultiplied.R, premultiplied.G, premultiplied.B, premultiplied.A = frostutil.MultiplyAlphaBytesPreserveColors(expected.R, expected.G, expected.B, expected.A)
+				ass.Equal(premultiplied, rImg.RGBAAt(x, y), "%v (%v, %v)", f, x, y)
+			}
+		}
+		// converting the unpacked image again doesn't change it
+		ass.Equal(pb.Pix, frostutil.NewPixelBufferFromImage(nImg, f).Pix, "%v", f)
+	}
+}
+
+func Test_PixelBufferDraw(t *testing.T) {
+	ass := assert.New(t)
+	pb := frostutil.NewPixelBuffer(image.Rect(10, 10, 30, 20), frostutil.PixelFormatRGB565)
+	ass.Equal(400, len(pb.Pix))
+	ass.Equal(color.NRGBA{0, 0, 0, 0xff}, pb.At(10, 10))
+	ass.Equal(color.NRGBA{}, pb.At(9, 10))
+
+	draw.Draw(pb, image.Rect(15, 12, 20, 14), image.NewUniform(color.RGBA{0xff, 0, 0, 0xff}), image.Point{}, draw.Src)
+	ass.Equal(color.NRGBA{0xff, 0, 0, 0xff}, pb.At(15, 12))
+	ass.Equal(color.NRGBA{0xff, 0, 0, 0xff}, pb.At(19, 13))
+	ass.Equal(color.NRGBA{0, 0, 0, 0xff}, pb.At(20, 13))
+	idx := pb.PixOffset(15, 12)
+	ass.Equal([]byte{0x00, 0xf8}, pb.Pix[idx:idx+2])
+	pb.Set(100, 100, color.White) // ignored
+
+	sub := pb.SubImage(image.Rect(18, 13, 40, 16)).(*frostutil.PixelBuffer)
+	ass.Equal(image.Rect(18, 13, 30, 16), sub.Bounds())
+	ass.Equal(color.NRGBA{0xff, 0, 0, 0xff}, sub.At(19, 13))
+	sub.Set(25, 15, color.NRGBA{0, 0xff, 0, 0xff})
+	ass.Equal(color.NRGBA{0, 0xff, 0, 0xff}, pb.At(25, 15))
+	ass.True(pb.SubImage(image.Rect(0, 0, 5, 5)).Bounds().Empty())
+
+	// the colors of transparent pixels are kept
+	rgba4444 := frostutil.NewPixelBuffer(image.Rect(0, 0, 2, 2), frostutil.PixelFormatRGBA4444)
+	rgba4444.Set(1, 1, color.NRGBA{0xff, 0x88, 0, 0})
+	ass.Equal(color.NRGBA{0xff, 0x88, 0, 0}, rgba4444.At(1, 1))
+	// including when they're unpacked to premultiplied colors
+	ass.Equal(color.RGBA{0xff, 0x88, 0, 0}, rgba4444.RGBAImage().RGBAAt(1, 1))
+	bgra := frostutil.NewPixelBuffer(image.Rect(0, 0, 1, 1), frostutil.PixelFormatBGRA8)
+	bgra.SetNRGBA(0, 0, color.NRGBA{0x12, 0x34, 0x56, 0})
+	ass.Equal(color.RGBA{0x12, 0x34, 0x56, 0}, bgra.RGBAImage().RGBAAt(0, 0))
+}
@@ -0,0 +1,344 @@
+package frostutil
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// This file traces polygons around the solid parts of images (for physics bodies, for instance), simplifies them, and breaks them into convex pieces.
+// Contours run along the edges between pixels, so their vertices are at pixel corners: a single solid pixel at (x, y) gives the square
+// (x, y), (x+1, y), (x+1, y+1), (x, y+1). Outer contours go clockwise on screen (with y pointing down) and holes go counterclockwise,
+// so PolygonArea is positive for outer contours and negative for holes.
+
+// ContourOptions controls TraceContours.
+type ContourOptions struct {
+	AlphaThreshold byte    // Pixels with alpha greater than this are solid.
+	Epsilon        float64 // If > 0, contours are simplified with SimplifyPolygon, with this tolerance in pixels.
+	MinArea        float64 // Contours (including holes) enclosing less than this many pixels are dropped, before simplifying.
+}
+
+// Contour is one closed outline traced by TraceContours.
+type Contour struct {
+	Points []image.Point // The vertices, in the image's coordinates. The last point connects back to the first, and isn't repeated.
+	Hole   bool          // True if this contour is the edge of a hole in a solid region, rather than the outside edge of one.
+}
+
+// contour directions, in clockwise order on screen
+const (
+	contourRight = iota
+	contourDown
+	contourLeft
+	contourUp
+)
+
+var contourSteps = [4]image.Point{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// TraceContours traces the outlines of every solid region in img with marching squares, including the outlines of holes, and returns them.
+// Pixels which only touch diagonally aren't connected, so they get separate contours.
+// The image is read with alphaValues, so *ebiten.Images are read with a single ReadPixels call.
+func TraceContours(img image.Image, opts ContourOptions) (contours []Contour) {
+	alpha, width, height := alphaValues(img)
+	solid := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < width && y < height && alpha[y*width+x] > opts.AlphaThreshold
+	}
+	// The edges between solid and non-solid pixels, going with the solid pixel on the right. Each vertex (pixel corner) can have
+	// up to two edges going out of it (when two solid pixels touch diagonally), stored as bits by direction.
+	vWidth := width + 1
+	edges := make([]uint8, vWidth*(height+1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !solid(x, y) {
+				continue
+			}
+			if !solid(x, y-1) {
+				edges[y*vWidth+x] |= 1 << contourRight
+			}
+			if !solid(x+1, y) {
+				edges[y*vWidth+x+1] |= 1 << contourDown
+			}
+			if !solid(x, y+1) {
+				edges[(y+1)*vWidth+x+1] |= 1 << contourLeft
+			}
+			if !solid(x-1, y) {
+				edges[(y+1)*vWidth+x] |= 1 << contourUp
+			}
+		}
+	}
+
+	offset := img.Bounds().Min
+	visited := make([]uint8, len(edges))
+	for start := range edges {
+		for firstDir := 0; firstDir < 4; firstDir++ {
+			if edges[start]&^visited[start]&(1<<firstDir) == 0 {
+				continue
+			}
+			// Follow the edges from here until we get back to the first one. When a vertex has two edges going out of it,
+			// we turn right, which keeps diagonally touching pixels separate. Every edge has exactly one edge after it that way, so the loops never cross.
+			var points []image.Point
+			pos := image.Pt(start%vWidth, start/vWidth)
+			dir := -1
+			for next := firstDir; dir < 0 || pos.Y*vWidth+pos.X != start || next != firstDir; {
+				if next != dir {
+					points = append(points, pos.Add(offset))
+				}
+				visited[pos.Y*vWidth+pos.X] |= 1 << next
+				dir = next
+				pos = pos.Add(contourSteps[dir])
+				out := edges[pos.Y*vWidth+pos.X]
+				for _, d := range [3]int{(dir + 1) & 3, dir, (dir + 3) & 3} {
+					if out&(1<<d) != 0 {
+						next = d
+						break
+					}
+				}
+			}
+			// the first point isn't a corner if we came back to it going the same way we left
+			if dir == firstDir {
+				points = points[1:]
+			}
+			area := PolygonArea(points)
+			if len(points) < 3 || math.Abs(area) < opts.MinArea {
+				continue
+			}
+			if opts.Epsilon > 0 {
+				points = SimplifyPolygon(points, opts.Epsilon)
+				if len(points) < 3 {
+					continue
+				}
+			}
+			contours = append(contours, Contour{Points: points, Hole: area < 0})
+		}
+	}
+	return
+}
+
+// PolygonArea returns the signed area of a closed polygon: positive if it goes clockwise on screen (with y pointing down), like outer contours,
+// and negative if it goes counterclockwise, like holes.
+func PolygonArea(points []image.Point) float64 {
+	var sum int
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		sum += p.X*q.Y - q.X*p.Y
+	}
+	return float64(sum) / 2
+}
+
+// pointLineDistance returns the distance from p to the line through a and b (or to a, if a and b are the same).
+func pointLineDistance(p, a, b image.Point) float64 {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return math.Hypot(float64(p.X-a.X), float64(p.Y-a.Y))
+	}
+	return math.Abs(dx*float64(a.Y-p.Y)-dy*float64(a.X-p.X)) / length
+}
+
+// simplifyPolyline runs Ramer–Douglas–Peucker on points, always keeping the first and last, and marks the points to keep in keep.
+func simplifyPolyline(points []image.Point, epsilon float64, keep []bool) {
+	if len(points) < 3 {
+		return
+	}
+	first, last := points[0], points[len(points)-1]
+	farthest, farthestDist := 0, -1.0
+	for i := 1; i < len(points)-1; i++ {
+		if d := pointLineDistance(points[i], first, last); d > farthestDist {
+			farthest, farthestDist = i, d
+		}
+	}
+	if farthestDist > epsilon {
+		keep[farthest] = true
+		simplifyPolyline(points[:farthest+1], epsilon, keep[:farthest+1])
+		simplifyPolyline(points[farthest:], epsilon, keep[farthest:])
+	}
+}
+
+// SimplifyPolygon simplifies a closed polygon with the Ramer–Douglas–Peucker algorithm, removing vertices until none of the removed ones are more than
+// epsilon pixels away from the simplified outline. The remaining vertices are a subset of the original ones, in the same order.
+// The polygon is split into two chains at its first vertex and the vertex farthest from it, which are always kept.
+func SimplifyPolygon(points []image.Point, epsilon float64) []image.Point {
+	if len(points) < 4 {
+		return points
+	}
+	farthest, farthestDist := 0, -1.0
+	for i, p := range points {
+		if d := math.Hypot(float64(p.X-points[0].X), float64(p.Y-points[0].Y)); d > farthestDist {
+			farthest, farthestDist = i, d
+		}
+	}
+	keep := make([]bool, len(points)+1)
+	keep[0], keep[farthest], keep[len(points)] = true, true, true
+	closed := append(append([]image.Point{}, points...), points[0])
+	simplifyPolyline(closed[:farthest+1], epsilon, keep[:farthest+1])
+	simplifyPolyline(closed[farthest:], epsilon, keep[farthest:])
+	var out []image.Point
+	for i, p := range points {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// cross returns the z component of the cross product of (b - a) and (c - b), which is positive if a, b, c turn clockwise on screen (with y pointing down).
+func cross(a, b, c image.Point) int {
+	return (b.X-a.X)*(c.Y-b.Y) - (b.Y-a.Y)*(c.X-b.X)
+}
+
+// ConvexHull returns the convex hull of points with Andrew's monotone chain algorithm, going clockwise on screen like outer contours.
+// Points on the hull's edges aren't included. If there are fewer than 3 distinct points, it returns them.
+func ConvexHull(points []image.Point) []image.Point {
+	sorted := append([]image.Point{}, points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+	unique := sorted[:0]
+	for i, p := range sorted {
+		if i == 0 || p != sorted[i-1] {
+			unique = append(unique, p)
+		}
+	}
+	if len(unique) < 3 {
+		return unique
+	}
+	hull := make([]image.Point, 0, 2*len(unique))
+	// the top chain, left to right, then the bottom chain, right to left
+	for _, p := range unique {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	top := len(hull) + 1
+	for i := len(unique) - 2; i >= 0; i-- {
+		p := unique[i]
+		for len(hull) >= top && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// ConvexDecompose breaks a simple polygon (one that doesn't cross itself, and has no holes) into convex polygons, with the Hertel–Mehlhorn algorithm:
+// it's triangulated by ear clipping, and then neighboring pieces are merged wherever the result is still convex. That gives at most four times
+// as many pieces as the fewest possible, and usually far fewer. The pieces go clockwise on screen, whichever way points went.
+// Physics engines generally want holes handled separately, so to decompose a solid region with holes, decompose its outer contour and subtract the holes,
+// or trace a mask which has the holes cut open.
+func ConvexDecompose(points []image.Point) [][]image.Point {
+	if len(points) < 3 {
+		return nil
+	}
+	poly := append([]image.Point{}, points...)
+	if PolygonArea(poly) < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+
+	// ear clipping, with pieces as lists of indices into poly
+	var pieces [][]int
+	remaining := make([]int, len(poly))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	for len(remaining) > 3 {
+		n := len(remaining)
+		ear := -1
+		for i := 0; i < n && ear < 0; i++ {
+			a, b, c := poly[remaining[(i+n-1)%n]], poly[remaining[i]], poly[remaining[(i+1)%n]]
+			if turn := cross(a, b, c); turn == 0 {
+				// b is in the middle of a straight edge, so it can go without making a piece
+				ear = i
+				break
+			} else if turn < 0 {
+				continue
+			}
+			isEar := true
+			for _, j := range remaining {
+				p := poly[j]
+				if p != a && p != b && p != c && cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0 {
+					isEar = false
+					break
+				}
+			}
+			if isEar {
+				ear = i
+			}
+		}
+		if ear < 0 {
+			// The polygon isn't simple (or has collinear runs we can't clip around), so clip the first convex vertex, or failing that, the first vertex.
+			ear = 0
+			for i := 0; i < n; i++ {
+				if cross(poly[remaining[(i+n-1)%n]], poly[remaining[i]], poly[remaining[(i+1)%n]]) > 0 {
+					ear = i
+					break
+				}
+			}
+		}
+		if cross(poly[remaining[(ear+n-1)%n]], poly[remaining[ear]], poly[remaining[(ear+1)%n]]) != 0 {
+			pieces = append(pieces, []int{remaining[(ear+n-1)%n], remaining[ear], remaining[(ear+1)%n]})
+		}
+		remaining = append(remaining[:ear], remaining[ear+1:]...)
+	}
+	if cross(poly[remaining[0]], poly[remaining[1]], poly[remaining[2]]) != 0 {
+		pieces = append(pieces, remaining)
+	}
+
+	// Hertel–Mehlhorn: merge pieces that share an edge, as long as the merged piece is still convex
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(pieces) && !merged; i++ {
+			for j := i + 1; j < len(pieces) && !merged; j++ {
+				if m := mergeConvexPieces(poly, pieces[i], pieces[j]); m != nil {
+					pieces[i] = m
+					pieces = append(pieces[:j], pieces[j+1:]...)
+					merged = true
+				}
+			}
+		}
+	}
+
+	out := make([][]image.Point, len(pieces))
+	for i, piece := range pieces {
+		for j, idx := range piece {
+			// skip vertices that ended up in the middle of straight edges
+			if cross(poly[piece[(j+len(piece)-1)%len(piece)]], poly[idx], poly[piece[(j+1)%len(piece)]]) != 0 {
+				out[i] = append(out[i], poly[idx])
+			}
+		}
+	}
+	return out
+}
+
+// mergeConvexPieces returns the union of pieces a and b (lists of indices into poly, both going clockwise) if they share an edge and the union is convex,
+// or nil otherwise.
+func mergeConvexPieces(poly []image.Point, a, b []int) []int {
+	for ai := range a {
+		// a goes from a[ai] to a[ai+1], so if b shares that edge, it goes the other way
+		from, to := a[ai], a[(ai+1)%len(a)]
+		for bi := range b {
+			if b[bi] != to || b[(bi+1)%len(b)] != from {
+				continue
+			}
+			// a starting at to and ending at from, then b from after from to before to
+			m := make([]int, 0, len(a)+len(b)-2)
+			for k := 0; k < len(a); k++ {
+				m = append(m, a[(ai+1+k)%len(a)])
+			}
+			for k := 2; k < len(b); k++ {
+				m = append(m, b[(bi+k)%len(b)])
+			}
+			for k := range m {
+				if cross(poly[m[(k+len(m)-1)%len(m)]], poly[m[k]], poly[m[(k+1)%len(m)]]) < 0 {
+					return nil
+				}
+			}
+			return m
+		}
+	}
+	return nil
+}
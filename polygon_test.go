@@ -0,0 +1,157 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeMaskImage makes an *image.Alpha at (ox, oy) from rows of '#' (solid) and '.' (empty) characters.
+func makeMaskImage(ox, oy int, rows ...string) *image.Alpha {
+	img := image.NewAlpha(image.Rect(ox, oy, ox+len(rows[0]), oy+len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				img.SetAlpha(ox+x, oy+y, color.Alpha{A: 255})
+			}
+		}
+	}
+	return img
+}
+
+func Test_TraceContours(t *testing.T) {
+	ass := assert.New(t)
+
+	contours := frostutil.TraceContours(makeMaskImage(10, 20, "...", ".#.", "..."), frostutil.ContourOptions{})
+	ass.Equal([]frostutil.Contour{{Points: []image.Point{{11, 21}, {12, 21}, {12, 22}, {11, 22}}}}, contours)
+
+	// a ring has an outer contour and a hole, and a separate island gets its own contour
+	img := makeMaskImage(0, 0,
+		"####..",
+		"#..#..",
+		"#..#.#",
+		"####.#",
+	)
+	contours = frostutil.TraceContours(img, frostutil.ContourOptions{})
+	require.Len(t, contours, 3)
+	ass.False(contours[0].Hole)
+	ass.Equal(16.0, frostutil.PolygonArea(contours[0].Points))
+	ass.Equal([]image.Point{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, contours[0].Points)
+	ass.True(contours[1].Hole)
+	ass.Equal(-4.0, frostutil.PolygonArea(contours[1].Points))
+	ass.False(contours[2].Hole)
+	ass.Equal([]image.Point{{5, 2}, {6, 2}, {6, 4}, {5, 4}}, contours[2].Points)
+
+	// the island is too small with MinArea
+	ass.Len(frostutil.TraceContours(img, frostutil.ContourOptions{MinArea: 3}), 2)
+
+	// pixels that only touch diagonally are separate
+	contours = frostutil.TraceContours(makeMaskImage(0, 0, "#.", ".#"), frostutil.ContourOptions{})
+	require.Len(t, contours, 2)
+	for _, c := range contours {
+		ass.Len(c.Points, 4)
+		ass.Equal(1.0, frostutil.PolygonArea(c.Points))
+	}
+
+	// an empty image has no contours
+	ass.Empty(frostutil.TraceContours(image.NewAlpha(image.Rect(0, 0, 4, 4)), frostutil.ContourOptions{}))
+}
+
+// makeCircleImage makes a size x size image with a solid circle filling it.
+func makeCircleImage(size int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	r := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if math.Hypot(float64(x)+0.5-r, float64(y)+0.5-r) < r {
+				img.SetNRGBA(x, y, color.NRGBA{A: 255})
+			}
+		}
+	}
+	return img
+}
+
+func Test_SimplifyPolygon(t *testing.T) {
+	ass := assert.New(t)
+	contours := frostutil.TraceContours(makeCircleImage(40), frostutil.ContourOptions{})
+	require.Len(t, contours, 1)
+	full := contours[0].Points
+	simplified := frostutil.SimplifyPolygon(full, 1)
+	ass.Less(len(simplified), len(full)/2)
+	ass.GreaterOrEqual(len(simplified), 8)
+	// the simplified polygon is a subset of the original, in order, and has about the same area
+	i := 0
+	for _, p := range simplified {
+		for i < len(full) && full[i] != p {
+			i++
+		}
+		ass.Less(i, len(full), "%v isn't in the original polygon, or is out of order", p)
+	}
+	ass.InDelta(frostutil.PolygonArea(full), frostutil.PolygonArea(simplified), frostutil.PolygonArea(full)*0.05)
+
+	withEpsilon := frostutil.TraceContours(makeCircleImage(40), frostutil.ContourOptions{Epsilon: 1})
+	require.Len(t, withEpsilon, 1)
+	ass.Equal(simplified, withEpsilon[0].Points)
+
+	square := []image.Point{{0, 0}, {5, 0}, {5, 5}, {0, 5}}
+	ass.Equal(square, frostutil.SimplifyPolygon(square, 1))
+	// with a big enough tolerance, it collapses to its diagonal
+	ass.Equal([]image.Point{{0, 0}, {5, 5}}, frostutil.SimplifyPolygon(square, 10))
+}
+
+// assertConvex checks that poly turns clockwise on screen (or goes straight) at every vertex.
+func assertConvex(ass *assert.Assertions, poly []image.Point) {
+	for i := range poly {
+		a, b, c := poly[(i+len(poly)-1)%len(poly)], poly[i], poly[(i+1)%len(poly)]
+		ass.GreaterOrEqual((b.X-a.X)*(c.Y-b.Y)-(b.Y-a.Y)*(c.X-b.X), 0, "%v isn't convex at %v", poly, b)
+	}
+}
+
+func Test_ConvexHullAndDecompose(t *testing.T) {
+	ass := assert.New(t)
+	lShape := frostutil.TraceContours(makeMaskImage(0, 0,
+		"##..",
+		"##..",
+		"####",
+	), frostutil.ContourOptions{})[0].Points
+	ass.Equal(8.0, frostutil.PolygonArea(lShape))
+
+	hull := frostutil.ConvexHull(lShape)
+	ass.Equal([]image.Point{{0, 0}, {2, 0}, {4, 2}, {4, 3}, {0, 3}}, hull)
+	assertConvex(ass, hull)
+
+	pieces := frostutil.ConvexDecompose(lShape)
+	ass.Len(pieces, 2)
+	area := 0.0
+	for _, piece := range pieces {
+		assertConvex(ass, piece)
+		area += frostutil.PolygonArea(piece)
+	}
+	ass.Equal(8.0, area)
+
+	// a more complicated shape, in the other direction
+	circle := frostutil.TraceContours(makeCircleImage(24), frostutil.ContourOptions{Epsilon: 0.5})[0].Points
+	star := append([]image.Point{}, circle...)
+	for i := 0; i < len(star); i += 3 {
+		// pull every third point towards the center to make it concave
+		star[i] = image.Pt((star[i].X+12)/2, (star[i].Y+12)/2)
+	}
+	for i, j := 0, len(star)-1; i < j; i, j = i+1, j-1 {
+		star[i], star[j] = star[j], star[i]
+	}
+	pieces = frostutil.ConvexDecompose(star)
+	area = 0
+	for _, piece := range pieces {
+		assertConvex(ass, piece)
+		area += frostutil.PolygonArea(piece)
+	}
+	ass.Equal(-frostutil.PolygonArea(star), area)
+	ass.Less(len(pieces), len(star)-2)
+
+	ass.Nil(frostutil.ConvexDecompose([]image.Point{{0, 0}, {1, 1}}))
+}
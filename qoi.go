@@ -0,0 +1,260 @@
+package frostutil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// This file implements the QOI ("Quite OK Image") format, as described at https://qoiformat.org/qoi-specification.pdf.
+// QOI is lossless like PNG, but much faster to encode and decode, which makes it handy for caches and test images.
+// QOI stores non-premultiplied RGBA, so we decode to *image.NRGBA, which also means color components are preserved where alpha is zero.
+
+const (
+	qoiMagic       = "qoif"
+	qoiHeaderLen   = 14
+	qoiMaxPixels   = 400000000 // the spec's limit, which also stops us from trying to allocate absurd amounts of memory for a corrupt header
+	qoiOpIndex     = 0x00      // 00xxxxxx
+	qoiOpDiff      = 0x40      // 01xxxxxx
+	qoiOpLuma      = 0x80      // 10xxxxxx
+	qoiOpRun       = 0xc0      // 11xxxxxx
+	qoiOpRGB       = 0xfe      // 11111110
+	qoiOpRGBA      = 0xff      // 11111111
+	qoiMask2       = 0xc0      // the top two bits, which identify the op (except for RGB and RGBA)
+	qoiMaxRun      = 62        // runs of 63 and 64 would collide with qoiOpRGB and qoiOpRGBA
+	qoiChannelsRGB = 3         // header channels value for images with no transparency
+	qoiChannelsA   = 4         // header channels value for images with transparency
+)
+
+var qoiEndMarker = []byte{0, 0, 0, 0, 0, 0, 0, 1}
+
+// qoiHash returns the index into the previously seen pixels array for the pixel px.
+func qoiHash(px [4]byte) byte {
+	return byte((int(px[0])*3 + int(px[1])*5 + int(px[2])*7 + int(px[3])*11) % 64)
+}
+
+func init() {
+	image.RegisterFormat("qoi", qoiMagic, DecodeQOI, DecodeQOIConfig)
+}
+
+// decodeQOIHeader reads the 14 byte QOI header from r and returns the image's width and height.
+func decodeQOIHeader(r io.Reader) (width, height int, err error) {
+	var header [qoiHeaderLen]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	if string(header[:4]) != qoiMagic {
+		err = errors.New("qoi: invalid magic number")
+		return
+	}
+	w := binary.BigEndian.Uint32(header[4:8])
+	h := binary.BigEndian.Uint32(header[8:12])
+	channels := header[12]
+	colorspace := header[13]
+	if w == 0 || h == 0 || uint64(w)*uint64(h) > qoiMaxPixels {
+		err = fmt.Errorf("qoi: invalid image dimensions %vx%v", w, h)
+	} else if channels != qoiChannelsRGB && channels != qoiChannelsA {
+		err = fmt.Errorf("qoi: invalid channel count %v", channels)
+	} else if colorspace > 1 {
+		err = fmt.Errorf("qoi: invalid colorspace %v", colorspace)
+	}
+	width, height = int(w), int(h)
+	return
+}
+
+// DecodeQOIConfig returns the color model and dimensions of a QOI image without decoding the entire image.
+func DecodeQOIConfig(r io.Reader) (cfg image.Config, err error) {
+	width, height, err := decodeQOIHeader(r)
+	if err == nil {
+		cfg = image.Config{ColorModel: color.NRGBAModel, Width: width, Height: height}
+	}
+	return
+}
+
+// DecodeQOI decodes a QOI image from r and returns it as an *image.NRGBA.
+func DecodeQOI(r io.Reader) (img image.Image, err error) {
+	width, height, err := decodeQOIHeader(r)
+	if err != nil {
+		return
+	}
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	nImg := image.NewNRGBA(image.Rect(0, 0, width, height))
+	pix := nImg.Pix // NewNRGBA gives us a stride of width*4, so the pixels are contiguous
+	var index [64][4]byte
+	px := [4]byte{0, 0, 0, 0xff}
+	run := 0
+	var b1, b2 byte
+	for idx := 0; idx < len(pix); idx += 4 {
+		if run > 0 {
+			run--
+		} else {
+			if b1, err = br.ReadByte(); err != nil {
+				break
+			}
+			if b1 == qoiOpRGB {
+				if px[0], err = br.ReadByte(); err == nil {
+					if px[1], err = br.ReadByte(); err == nil {
+						px[2], err = br.ReadByte()
+					}
+				}
+			} else if b1 == qoiOpRGBA {
+				if px[0], err = br.ReadByte(); err == nil {
+					if px[1], err = br.ReadByte(); err == nil {
+						if px[2], err = br.ReadByte(); err == nil {
+							px[3], err = br.ReadByte()
+						}
+					}
+				}
+			} else {
+				switch b1 & qoiMask2 {
+				case qoiOpIndex:
+					px = index[b1]
+				case qoiOpDiff:
+					// each difference is stored with a bias of 2, and byte arithmetic wraps around just like the spec wants
+					px[0] += (b1>>4)&0x03 - 2
+					px[1] += (b1>>2)&0x03 - 2
+					px[2] += b1&0x03 - 2
+				case qoiOpLuma:
+					if b2, err = br.ReadByte(); err == nil {
+						vg := b1&0x3f - 32
+						px[0] += vg - 8 + (b2>>4)&0x0f
+						px[1] += vg
+						px[2] += vg - 8 + b2&0x0f
+					}
+				case qoiOpRun:
+					run = int(b1 & 0x3f)
+				}
+			}
+			if err != nil {
+				break
+			}
+			index[qoiHash(px)] = px
+		}
+		pix[idx] = px[0]
+		pix[idx+1] = px[1]
+		pix[idx+2] = px[2]
+		pix[idx+3] = px[3]
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	img = nImg
+	return
+}
+
+// EncodeQOI writes img to w in QOI format.
+// *image.NRGBA images are encoded straight from their pixel buffers, and *image.RGBA images have their alpha premultiplication removed
+// with UnmultiplyAlphaBytes as they're encoded. Other images (including *ebiten.Images) are converted with NewNRGBAFromImage first.
+// If every pixel is opaque, the header says the image has 3 channels, and otherwise it says 4. Either way the pixel data is the same.
+func EncodeQOI(w io.Writer, img image.Image) (err error) {
+	img = unwrapSnapshot(img)
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || uint64(width)*uint64(height) > qoiMaxPixels {
+		return fmt.Errorf("qoi: can't encode an image with dimensions %vx%v", width, height)
+	}
+	var pix []byte
+	stride := 0
+	premultiplied := false
+	if nImg, ok := img.(*image.NRGBA); ok {
+		pix = nImg.Pix[nImg.PixOffset(bounds.Min.X, bounds.Min.Y):]
+		stride = nImg.Stride
+	} else if rImg, ok := img.(*image.RGBA); ok {
+		pix = rImg.Pix[rImg.PixOffset(bounds.Min.X, bounds.Min.Y):]
+		stride = rImg.Stride
+		premultiplied = true
+	} else {
+		nImg := NewNRGBAFromImage(img)
+		pix = nImg.Pix
+		stride = nImg.Stride
+	}
+
+	channels := byte(qoiChannelsRGB)
+	for y := 0; y < height && channels == qoiChannelsRGB; y++ {
+		for idx := y * stride; idx < y*stride+width*4; idx += 4 {
+			if pix[idx+3] != 0xff {
+				channels = qoiChannelsA
+				break
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	var header [qoiHeaderLen]byte
+	copy(header[:4], qoiMagic)
+	binary.BigEndian.PutUint32(header[4:8], uint32(width))
+	binary.BigEndian.PutUint32(header[8:12], uint32(height))
+	header[12] = channels
+	header[13] = 0 // sRGB with linear alpha
+	bw.Write(header[:])
+
+	var index [64][4]byte
+	prev := [4]byte{0, 0, 0, 0xff}
+	var px [4]byte
+	run := 0
+	for y := 0; y < height; y++ {
+		for idx := y * stride; idx < y*stride+width*4; idx += 4 {
+			if premultiplied {
+				px[0], px[1], px[2], px[3] = UnmultiplyAlphaBytes(pix[idx], pix[idx+1], pix[idx+2], pix[idx+3])
+			} else {
+				px[0], px[1], px[2], px[3] = pix[idx], pix[idx+1], pix[idx+2], pix[idx+3]
+			}
+			if px == prev {
+				run++
+				if run == qoiMaxRun {
+					bw.WriteByte(qoiOpRun | byte(run-1))
+					run = 0
+				}
+				continue
+			}
+			if run > 0 {
+				bw.WriteByte(qoiOpRun | byte(run-1))
+				run = 0
+			}
+			hash := qoiHash(px)
+			if index[hash] == px {
+				bw.WriteByte(qoiOpIndex | hash)
+			} else {
+				index[hash] = px
+				if px[3] == prev[3] {
+					// the differences wrap around, so we treat them as signed bytes
+					vr := int(int8(px[0] - prev[0]))
+					vg := int(int8(px[1] - prev[1]))
+					vb := int(int8(px[2] - prev[2]))
+					vgr := vr - vg
+					vgb := vb - vg
+					if vr > -3 && vr < 2 && vg > -3 && vg < 2 && vb > -3 && vb < 2 {
+						bw.WriteByte(qoiOpDiff | byte(vr+2)<<4 | byte(vg+2)<<2 | byte(vb+2))
+					} else if vgr > -9 && vgr < 8 && vg > -33 && vg < 32 && vgb > -9 && vgb < 8 {
+						bw.WriteByte(qoiOpLuma | byte(vg+32))
+						bw.WriteByte(byte(vgr+8)<<4 | byte(vgb+8))
+					} else {
+						bw.Write([]byte{qoiOpRGB, px[0], px[1], px[2]})
+					}
+				} else {
+					bw.Write([]byte{qoiOpRGBA, px[0], px[1], px[2], px[3]})
+				}
+			}
+			prev = px
+		}
+	}
+	if run > 0 {
+		bw.WriteByte(qoiOpRun | byte(run-1))
+	}
+	bw.Write(qoiEndMarker)
+	// bufio.Writer remembers the first error from any write, so we only need to check it here.
+	return bw.Flush()
+}
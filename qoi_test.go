@@ -0,0 +1,119 @@
+package frostutil_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Checks the exact bytes EncodeQOI produces for a tiny image which uses the run, diff, and RGBA ops.
+func Test_EncodeQOIBytes(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{1, 1, 1, 255})
+	img.SetNRGBA(2, 0, color.NRGBA{1, 1, 1, 255})
+	img.SetNRGBA(3, 0, color.NRGBA{200, 10, 20, 128})
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeQOI(&buf, img))
+	expected := []byte{
+		'q', 'o', 'i', 'f', 0, 0, 0, 4, 0, 0, 0, 1, 4, 0, // header
+		0xc0,                   // run of 1 matching the starting pixel
+		0x7f,                   // diff of +1, +1, +1
+		0xc0,                   // run of 1
+		0xff, 200, 10, 20, 128, // rgba
+		0, 0, 0, 0, 0, 0, 0, 1, // end marker
+	}
+	assert.Equal(t, expected, buf.Bytes())
+}
+
+func Test_QOIRoundTrip(t *testing.T) {
+	ass := assert.New(t)
+	for alphaTestMode := AlphaTestMode(0); alphaTestMode < NumAlphaTestModes; alphaTestMode++ {
+		var buf bytes.Buffer
+		require.NoError(t, frostutil.EncodeQOI(&buf, GetTestImageNRGBA(alphaTestMode)))
+		img, err := frostutil.DecodeQOI(&buf)
+		require.NoError(t, err)
+		ass.NoError(CheckImagePattern(img, alphaTestMode))
+	}
+
+	// Opaque premultiplied images should come back unchanged.
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeQOI(&buf, GetTestImageRGBA(Alpha_FF)))
+	ass.Equal(byte(3), buf.Bytes()[12])
+	img, err := frostutil.DecodeQOI(&buf)
+	require.NoError(t, err)
+	ass.NoError(CheckImagePattern(frostutil.CopyImage(img, false), Alpha_FF))
+
+	// Noise with a palette of a few colors, a long run, and a sub-image, to exercise the index and luma ops, runs longer than 62, and strides.
+	rnd := rand.New(rand.NewSource(1))
+	palette := []color.NRGBA{{10, 20, 30, 255}, {12, 25, 28, 255}, {200, 100, 50, 40}, {0, 0, 0, 0}, {255, 0, 255, 0}}
+	noise := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			if y < 100 {
+				noise.SetNRGBA(x, y, palette[rnd.Intn(len(palette))])
+			} else {
+				noise.SetNRGBA(x, y, palette[0])
+			}
+		}
+	}
+	sub := noise.SubImage(image.Rect(5, 7, 290, 180)).(*image.NRGBA)
+	buf.Reset()
+	require.NoError(t, frostutil.EncodeQOI(&buf, sub))
+	decoded, err := frostutil.DecodeQOI(&buf)
+	require.NoError(t, err)
+	nDecoded := decoded.(*image.NRGBA)
+	ass.Equal(image.Rect(0, 0, 285, 173), nDecoded.Bounds())
+	for y := 0; y < 173; y++ {
+		for x := 0; x < 285; x++ {
+			if sub.NRGBAAt(x+5, y+7) != nDecoded.NRGBAAt(x, y) {
+				ass.Failf("QOI round trip mismatch", "pixel (%v, %v): expected %v, got %v", x, y, sub.NRGBAAt(x+5, y+7), nDecoded.NRGBAAt(x, y))
+				return
+			}
+		}
+	}
+}
+
+func Test_QOIRegistered(t *testing.T) {
+	ass := assert.New(t)
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeQOI(&buf, GetTestImageNRGBA(Alpha_DiagonalGradient)))
+	data := buf.Bytes()
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	ass.Equal("qoi", format)
+	ass.Equal(256, cfg.Width)
+	ass.Equal(256, cfg.Height)
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	ass.Equal("qoi", format)
+	ass.NoError(CheckImagePattern(img, Alpha_DiagonalGradient))
+
+	img, imageFormat, err := frostutil.DecodeImage(bytes.NewReader(data))
+	require.NoError(t, err)
+	ass.Equal(frostutil.ImageFormatQOI, imageFormat)
+	ass.NoError(CheckImagePattern(img, Alpha_DiagonalGradient))
+}
+
+func Test_DecodeQOIErrors(t *testing.T) {
+	ass := assert.New(t)
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeQOI(&buf, GetTestImageNRGBA(Alpha_DiagonalGradient)))
+	data := buf.Bytes()
+	_, err := frostutil.DecodeQOI(bytes.NewReader(data[:len(data)/2]))
+	ass.Error(err)
+	_, err = frostutil.DecodeQOI(bytes.NewReader(data[:10]))
+	ass.Error(err)
+	_, err = frostutil.DecodeQOI(bytes.NewReader([]byte("qoxf\x00\x00\x00\x01\x00\x00\x00\x01\x04\x00")))
+	ass.Error(err)
+	_, err = frostutil.DecodeQOI(bytes.NewReader([]byte("qoif\xff\xff\xff\xff\xff\xff\xff\xff\x04\x00")))
+	ass.Error(err)
+}
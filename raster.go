@@ -0,0 +1,259 @@
+package frostutil
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// This file is a small anti-aliased vector rasterizer for drawing on the CPU, mainly so that drawing code can have golden tests (with MatchesImage)
+// that don't need a GPU. Shapes are turned into polygons, their coverage of each pixel is worked out into an *image.Alpha mask, and the color is
+// drawn through the mask with draw.DrawMask and draw.Over, so the compositing is the standard library's alpha-premultiplied source-over math.
+// It works with any draw.Image, and is fastest with *image.RGBA.
+
+// Vec2 is a point or vector with float64 coordinates, for the rasterizer.
+type Vec2 struct {
+	X, Y float64
+}
+
+// FillRule says which parts of overlapping or self-intersecting polygons are filled.
+type FillRule int
+
+const (
+	FillNonZero FillRule = iota // Fill everywhere the contours wind around a nonzero number of times, so contours going opposite ways cut holes.
+	FillEvenOdd                 // Fill everywhere an odd number of contours overlap, whichever way they go.
+)
+
+// rasterSubsamples is how many sub-scanlines each row of pixels is divided into. Coverage along each sub-scanline is exact.
+const rasterSubsamples = 16
+
+// rasterEdge is a non-horizontal polygon edge, from the top to the bottom.
+type rasterEdge struct {
+	x0, y0, x1, y1 float64
+	winding        int // +1 if the edge originally went down, -1 if it went up
+}
+
+// rasterCoverage works out how much of each pixel inside clip is covered by the contours, filled with the given rule, and returns it as a mask
+// covering the contours' bounding box (within clip). It returns nil if nothing is covered.
+func rasterCoverage(contours [][]Vec2, rule FillRule, clip image.Rectangle) *image.Alpha {
+	var edges []rasterEdge
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, contour := range contours {
+		for i, p := range contour {
+			q := contour[(i+1)%len(contour)]
+			minX, minY, maxX, maxY = math.Min(minX, p.X), math.Min(minY, p.Y), math.Max(maxX, p.X), math.Max(maxY, p.Y)
+			if p.Y == q.Y || math.IsNaN(p.Y) || math.IsNaN(q.Y) {
+				continue
+			}
+			if p.Y < q.Y {
+				edges = append(edges, rasterEdge{p.X, p.Y, q.X, q.Y, 1})
+			} else {
+				edges = append(edges, rasterEdge{q.X, q.Y, p.X, p.Y, -1})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(clip)
+	if bounds.Empty() {
+		return nil
+	}
+	mask := image.NewAlpha(bounds)
+	width := bounds.Dx()
+	row := make([]float64, width)
+	type crossing struct {
+		x       float64
+		winding int
+	}
+	var crossings []crossing
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+		for sub := 0; sub < rasterSubsamples; sub++ {
+			sy := float64(y) + (float64(sub)+0.5)/rasterSubsamples
+			crossings = crossings[:0]
+			for _, e := range edges {
+				if sy >= e.y0 && sy < e.y1 {
+					crossings = append(crossings, crossing{e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.winding})
+				}
+			}
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding := 0
+			for i, c := range crossings[:Max(len(crossings)-1, 0)] {
+				winding += c.winding
+				inside := winding != 0
+				if rule == FillEvenOdd {
+					inside = winding&1 != 0
+				}
+				if inside {
+					addSpanCoverage(row, crossings[i].x-float64(bounds.Min.X), crossings[i+1].x-float64(bounds.Min.X), 1.0/rasterSubsamples)
+				}
+			}
+		}
+		for x, c := range row {
+			mask.Pix[(y-bounds.Min.Y)*mask.Stride+x] = byte(math.Min(c, 1)*0xff + 0.5)
+		}
+	}
+	return mask
+}
+
+// addSpanCoverage adds amount times the covered fraction of each pixel in row that the span from x0 to x1 covers.
+func addSpanCoverage(row []float64, x0, x1, amount float64) {
+	x0, x1 = math.Max(x0, 0), math.Min(x1, float64(len(row)))
+	if x1 <= x0 {
+		return
+	}
+	first, last := int(x0), int(math.Ceil(x1))-1
+	if first == last {
+		row[first] += (x1 - x0) * amount
+		return
+	}
+	row[first] += (float64(first+1) - x0) * amount
+	for x := first + 1; x < last; x++ {
+		row[x] += amount
+	}
+	row[last] += (x1 - float64(last)) * amount
+}
+
+// FillPolygon fills the polygon made of the given contours (each of which is closed automatically) with col, using the given fill rule.
+// Coordinates are in dst's coordinate space, with pixel (x, y) covering the square from (x, y) to (x+1, y+1).
+func FillPolygon(dst draw.Image, contours [][]Vec2, rule FillRule, col color.Color) {
+	mask := rasterCoverage(contours, rule, dst.Bounds())
+	if mask == nil {
+		return
+	}
+	draw.DrawMask(dst, mask.Rect, image.NewUniform(col), image.Point{}, mask, mask.Rect.Min, draw.Over)
+}
+
+// DrawLine draws a line from (x0, y0) to (x1, y1) with the given width and flat ends, which stop exactly at the end points.
+func DrawLine(dst draw.Image, x0, y0, x1, y1, width float64, col color.Color) {
+	dx, dy := x1-x0, y1-y0
+	length := math.Hypot(dx, dy)
+	if length == 0 || width <= 0 {
+		return
+	}
+	// the offset from the middle of the line to its sides
+	nx, ny := -dy/length*width/2, dx/length*width/2
+	FillPolygon(dst, [][]Vec2{{{x0 + nx, y0 + ny}, {x1 + nx, y1 + ny}, {x1 - nx, y1 - ny}, {x0 - nx, y0 - ny}}}, FillNonZero, col)
+}
+
+// arcSegments returns how many straight segments a full circle with the given radius needs so that they stay within a tenth of a pixel of it.
+// It's always a multiple of 8, so that circles are symmetrical and quarter circles have whole numbers of segments.
+func arcSegments(radius float64) int {
+	n := 8
+	if radius > 0.1 {
+		n = int(math.Ceil(math.Pi / math.Acos(1-0.1/radius)))
+	}
+	return Max((n+7)/8*8, 8)
+}
+
+// arcScale returns how much to push out the vertices between the ends of arcs made of segments covering the given angle, so that the polygon
+// has the same area as the real curve instead of falling inside it.
+func arcScale(angle float64) float64 {
+	return math.Sqrt(angle / math.Sin(angle))
+}
+
+// appendArc appends points along the arc of the ellipse centered at (cx, cy) with radii rx and ry, from angle start to end (in radians,
+// going clockwise on screen for increasing angles). The end points are exactly on the ellipse, so arcs join straight lines cleanly.
+// If closed is true, the arc is a full ellipse, and the end (the same point as the start) isn't included, so all the points are pushed out.
+func appendArc(points []Vec2, cx, cy, rx, ry, start, end float64, closed bool) []Vec2 {
+	n := Max(arcSegments(math.Max(rx, ry))*int(math.Round(math.Abs(end-start)/(math.Pi/4)))/8, 1)
+	scale := arcScale(math.Abs(end-start) / float64(n))
+	for i := 0; i <= n; i++ {
+		a := start + (end-start)*float64(i)/float64(n)
+		if closed && i == n {
+			break
+		}
+		if closed || (i > 0 && i < n) {
+			points = append(points, Vec2{cx + rx*scale*math.Cos(a), cy + ry*scale*math.Sin(a)})
+		} else {
+			points = append(points, Vec2{cx + rx*math.Cos(a), cy + ry*math.Sin(a)})
+		}
+	}
+	return points
+}
+
+// ellipseContour returns the polygon for an ellipse, going clockwise on screen, or counterclockwise if reverse is true.
+func ellipseContour(cx, cy, rx, ry float64, reverse bool) []Vec2 {
+	if reverse {
+		return appendArc(nil, cx, cy, rx, ry, 0, -2*math.Pi, true)
+	}
+	return appendArc(nil, cx, cy, rx, ry, 0, 2*math.Pi, true)
+}
+
+// FillEllipse fills the ellipse centered at (cx, cy) with horizontal radius rx and vertical radius ry.
+func FillEllipse(dst draw.Image, cx, cy, rx, ry float64, col color.Color) {
+	if rx <= 0 || ry <= 0 {
+		return
+	}
+	FillPolygon(dst, [][]Vec2{ellipseContour(cx, cy, rx, ry, false)}, FillNonZero, col)
+}
+
+// StrokeEllipse draws the outline of the ellipse centered at (cx, cy) with radii rx and ry, with the given width, centered on the ellipse.
+func StrokeEllipse(dst draw.Image, cx, cy, rx, ry, width float64, col color.Color) {
+	if rx <= 0 || ry <= 0 || width <= 0 {
+		return
+	}
+	contours := [][]Vec2{ellipseContour(cx, cy, rx+width/2, ry+width/2, false)}
+	if rx > width/2 && ry > width/2 {
+		contours = append(contours, ellipseContour(cx, cy, rx-width/2, ry-width/2, true))
+	}
+	FillPolygon(dst, contours, FillNonZero, col)
+}
+
+// FillCircle fills the circle centered at (cx, cy) with radius r.
+func FillCircle(dst draw.Image, cx, cy, r float64, col color.Color) {
+	FillEllipse(dst, cx, cy, r, r, col)
+}
+
+// StrokeCircle draws the outline of the circle centered at (cx, cy) with radius r, with the given width, centered on the circle.
+func StrokeCircle(dst draw.Image, cx, cy, r, width float64, col color.Color) {
+	StrokeEllipse(dst, cx, cy, r, r, width, col)
+}
+
+// roundedRectContour returns the polygon for the rectangle from (x, y) to (x+w, y+h) with corners rounded with the given radius
+// (limited to half the width or height), going clockwise on screen, or counterclockwise if reverse is true.
+func roundedRectContour(x, y, w, h, radius float64, reverse bool) []Vec2 {
+	radius = math.Min(math.Max(radius, 0), math.Min(w, h)/2)
+	var points []Vec2
+	if radius == 0 {
+		points = []Vec2{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}
+	} else {
+		points = appendArc(points, x+w-radius, y+radius, radius, radius, -math.Pi/2, 0, false)
+		points = appendArc(points, x+w-radius, y+h-radius, radius, radius, 0, math.Pi/2, false)
+		points = appendArc(points, x+radius, y+h-radius, radius, radius, math.Pi/2, math.Pi, false)
+		points = appendArc(points, x+radius, y+radius, radius, radius, math.Pi, 3*math.Pi/2, false)
+	}
+	if reverse {
+		for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+	return points
+}
+
+// FillRoundedRect fills the rectangle from (x, y) to (x+w, y+h), with its corners rounded with the given radius (0 for square corners).
+func FillRoundedRect(dst draw.Image, x, y, w, h, radius float64, col color.Color) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	FillPolygon(dst, [][]Vec2{roundedRectContour(x, y, w, h, radius, false)}, FillNonZero, col)
+}
+
+// StrokeRoundedRect draws the outline of the rectangle from (x, y) to (x+w, y+h), with its corners rounded with the given radius,
+// with the given width, centered on the rectangle's edges.
+func StrokeRoundedRect(dst draw.Image, x, y, w, h, radius, width float64, col color.Color) {
+	if w <= 0 || h <= 0 || width <= 0 {
+		return
+	}
+	half := width / 2
+	contours := [][]Vec2{roundedRectContour(x-half, y-half, w+width, h+width, radius+half, false)}
+	if w > width && h > width {
+		contours = append(contours, roundedRectContour(x+half, y+half, w-width, h-width, radius-half, true))
+	}
+	FillPolygon(dst, contours, FillNonZero, col)
+}
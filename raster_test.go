@@ -0,0 +1,224 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// alphaSum returns the total alpha of img's pixels, in pixels (so a fully covered pixel counts as 1).
+func alphaSum(img *image.RGBA) float64 {
+	var sum float64
+	for i := 3; i < len(img.Pix); i += 4 {
+		sum += float64(img.Pix[i]) / 255
+	}
+	return sum
+}
+
+func Test_FillPolygon(t *testing.T) {
+	ass := assert.New(t)
+	red := color.RGBA{255, 0, 0, 255}
+
+	// a square on pixel boundaries covers whole pixels, and one on half pixels covers half of the pixels along its edges
+	img := image.NewRGBA(image.Rect(0, 0, 6, 6))
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{{{1, 1}, {4, 1}, {4, 3}, {1, 3}}}, frostutil.FillNonZero, red)
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			if x >= 1 && x < 4 && y >= 1 && y < 3 {
+				ass.Equal(red, img.RGBAAt(x, y), "(%v, %v)", x, y)
+			} else {
+				ass.Equal(color.RGBA{}, img.RGBAAt(x, y), "(%v, %v)", x, y)
+			}
+		}
+	}
+	img = image.NewRGBA(image.Rect(0, 0, 6, 6))
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{{{1.5, 1}, {4, 1}, {4, 3}, {1.5, 3}}}, frostutil.FillNonZero, red)
+	ass.Equal(color.RGBA{128, 0, 0, 128}, img.RGBAAt(1, 2))
+	ass.Equal(red, img.RGBAAt(2, 2))
+	ass.InDelta(5, alphaSum(img), 0.01)
+
+	// two overlapping squares going the same way: nonzero fills where they overlap, and even-odd doesn't
+	squares := [][]frostutil.Vec2{
+		{{0, 0}, {4, 0}, {4, 4}, {0, 4}},
+		{{2, 2}, {6, 2}, {6, 6}, {2, 6}},
+	}
+	img = image.NewRGBA(image.Rect(0, 0, 6, 6))
+	frostutil.FillPolygon(img, squares, frostutil.FillNonZero, red)
+	ass.Equal(red, img.RGBAAt(3, 3))
+	ass.InDelta(28, alphaSum(img), 0.01)
+	img = image.NewRGBA(image.Rect(0, 0, 6, 6))
+	frostutil.FillPolygon(img, squares, frostutil.FillEvenOdd, red)
+	ass.Equal(color.RGBA{}, img.RGBAAt(3, 3))
+	ass.Equal(red, img.RGBAAt(1, 1))
+	ass.InDelta(24, alphaSum(img), 0.01)
+
+	// with nonzero, a contour going the other way cuts a hole
+	img = image.NewRGBA(image.Rect(0, 0, 6, 6))
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{
+		{{0, 0}, {6, 0}, {6, 6}, {0, 6}},
+		{{2, 2}, {2, 4}, {4, 4}, {4, 2}},
+	}, frostutil.FillNonZero, red)
+	ass.Equal(color.RGBA{}, img.RGBAAt(3, 3))
+	ass.InDelta(32, alphaSum(img), 0.01)
+
+	// a self-intersecting star: the middle is filled with nonzero, but not even-odd
+	star := make([]frostutil.Vec2, 5)
+	for i := range star {
+		a := float64(i)*4*math.Pi/5 - math.Pi/2
+		star[i] = frostutil.Vec2{X: 10 + 9*math.Cos(a), Y: 10 + 9*math.Sin(a)}
+	}
+	img = image.NewRGBA(image.Rect(0, 0, 20, 20))
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{star}, frostutil.FillNonZero, red)
+	ass.Equal(red, img.RGBAAt(10, 10))
+	img = image.NewRGBA(image.Rect(0, 0, 20, 20))
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{star}, frostutil.FillEvenOdd, red)
+	ass.Equal(color.RGBA{}, img.RGBAAt(10, 10))
+
+	// shapes are clipped to the image, which doesn't have to start at (0, 0)
+	img = image.NewRGBA(image.Rect(10, 10, 14, 14))
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{{{-100, -100}, {12, -100}, {12, 100}, {-100, 100}}}, frostutil.FillNonZero, red)
+	ass.Equal(red, img.RGBAAt(11, 13))
+	ass.Equal(color.RGBA{}, img.RGBAAt(12, 13))
+	ass.InDelta(8, alphaSum(img), 0.01)
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{{{0, 0}, {5, 0}, {5, 5}}}, frostutil.FillNonZero, red)
+	ass.InDelta(8, alphaSum(img), 0.01)
+}
+
+func Test_FillPolygon_Compositing(t *testing.T) {
+	ass := assert.New(t)
+	translucent := color.NRGBA{0, 0, 255, 128}
+	square := [][]frostutil.Vec2{{{0, 0}, {2, 0}, {2, 2}, {0, 2}}}
+
+	// drawing over an image gives the same result as image/draw's source-over with premultiplied colors, in both kinds of images
+	for _, dst := range []draw.Image{image.NewRGBA(image.Rect(0, 0, 2, 2)), image.NewNRGBA(image.Rect(0, 0, 2, 2))} {
+		expected := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		for _, c := range []color.Color{color.NRGBA{255, 200, 0, 255}, color.NRGBA{255, 0, 0, 100}, color.NRGBA{}} {
+			draw.Draw(dst, dst.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+			frostutil.FillPolygon(dst, square, frostutil.FillNonZero, translucent)
+			draw.Draw(expected, expected.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+			draw.Draw(expected, expected.Bounds(), image.NewUniform(translucent), image.Point{}, draw.Over)
+			r0, g0, b0, a0 := expected.At(1, 1).RGBA()
+			r1, g1, b1, a1 := dst.At(1, 1).RGBA()
+			ass.InDelta(r0>>8, r1>>8, 1, "%T over %v", dst, c)
+			ass.InDelta(g0>>8, g1>>8, 1, "%T over %v", dst, c)
+			ass.InDelta(b0>>8, b1>>8, 1, "%T over %v", dst, c)
+			ass.InDelta(a0>>8, a1>>8, 1, "%T over %v", dst, c)
+		}
+	}
+
+	// partial coverage scales the source: a half-covered pixel of opaque blue over opaque white is half of each
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	frostutil.FillPolygon(img, [][]frostutil.Vec2{{{0, 0}, {1.5, 0}, {1.5, 1}, {0, 1}}}, frostutil.FillNonZero, color.RGBA{0, 0, 255, 255})
+	ass.Equal(color.RGBA{0, 0, 255, 255}, img.RGBAAt(0, 0))
+	ass.Equal(color.RGBA{127, 127, 255, 255}, img.RGBAAt(1, 0))
+}
+
+func Test_DrawLine(t *testing.T) {
+	ass := assert.New(t)
+	white := color.RGBA{255, 255, 255, 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	frostutil.DrawLine(img, 1, 2, 7, 2, 2, white)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			if x >= 1 && x < 7 && y >= 1 && y < 3 {
+				ass.Equal(white, img.RGBAAt(x, y), "(%v, %v)", x, y)
+			} else {
+				ass.Equal(color.RGBA{}, img.RGBAAt(x, y), "(%v, %v)", x, y)
+			}
+		}
+	}
+
+	// a diagonal line covers its length times its width
+	img = image.NewRGBA(image.Rect(0, 0, 20, 20))
+	frostutil.DrawLine(img, 4, 4, 16, 13, 3, white)
+	ass.InDelta(15*3, alphaSum(img), 0.1)
+	ass.Equal(white, img.RGBAAt(10, 8))
+	ass.Equal(color.RGBA{}, img.RGBAAt(4, 13))
+
+	// zero-length and zero-width lines draw nothing
+	img = image.NewRGBA(image.Rect(0, 0, 4, 4))
+	frostutil.DrawLine(img, 2, 2, 2, 2, 3, white)
+	frostutil.DrawLine(img, 0, 0, 4, 4, 0, white)
+	ass.Equal(0.0, alphaSum(img))
+}
+
+func Test_Ellipses(t *testing.T) {
+	ass := assert.New(t)
+	white := color.RGBA{255, 255, 255, 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	frostutil.FillCircle(img, 16, 16, 10, white)
+	ass.InDelta(math.Pi*100, alphaSum(img), 0.5)
+	ass.Equal(white, img.RGBAAt(16, 16))
+	ass.Equal(white, img.RGBAAt(24, 16))
+	ass.Equal(color.RGBA{}, img.RGBAAt(27, 16))
+	ass.Equal(color.RGBA{}, img.RGBAAt(24, 24))
+	// it's symmetrical
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			ass.InDelta(img.RGBAAt(x, y).A, img.RGBAAt(31-x, y).A, 1, "(%v, %v)", x, y)
+			ass.InDelta(img.RGBAAt(x, y).A, img.RGBAAt(y, x).A, 1, "(%v, %v)", x, y)
+		}
+	}
+
+	img = image.NewRGBA(image.Rect(0, 0, 32, 32))
+	frostutil.FillEllipse(img, 16, 16, 12, 5, white)
+	ass.InDelta(math.Pi*60, alphaSum(img), 0.5)
+	ass.Equal(white, img.RGBAAt(26, 16))
+	ass.Equal(color.RGBA{}, img.RGBAAt(16, 22))
+
+	// a ring is centered on the radius
+	img = image.NewRGBA(image.Rect(0, 0, 32, 32))
+	frostutil.StrokeCircle(img, 16, 16, 10, 3, white)
+	ass.InDelta(math.Pi*(11.5*11.5-8.5*8.5), alphaSum(img), 0.5)
+	ass.Equal(color.RGBA{}, img.RGBAAt(16, 16))
+	ass.Equal(white, img.RGBAAt(25, 16))
+	ass.Equal(white, img.RGBAAt(6, 16))
+	ass.Equal(color.RGBA{}, img.RGBAAt(28, 16))
+
+	// a stroke wider than the ellipse fills it
+	img = image.NewRGBA(image.Rect(0, 0, 32, 32))
+	frostutil.StrokeEllipse(img, 16, 16, 6, 3, 8, white)
+	ass.InDelta(math.Pi*10*7, alphaSum(img), 0.5)
+	ass.Equal(white, img.RGBAAt(16, 16))
+}
+
+func Test_RoundedRects(t *testing.T) {
+	ass := assert.New(t)
+	white := color.RGBA{255, 255, 255, 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 24, 16))
+	frostutil.FillRoundedRect(img, 2, 2, 20, 12, 4, white)
+	ass.InDelta(20*12-(4-math.Pi)*16, alphaSum(img), 0.5)
+	ass.Equal(white, img.RGBAAt(12, 2))
+	ass.Equal(white, img.RGBAAt(2, 8))
+	ass.Equal(color.RGBA{}, img.RGBAAt(2, 2))
+	ass.Equal(color.RGBA{}, img.RGBAAt(21, 13))
+
+	// with no radius, it's a plain rectangle, and the radius is limited to half the smaller side
+	img = image.NewRGBA(image.Rect(0, 0, 24, 16))
+	frostutil.FillRoundedRect(img, 2, 2, 20, 12, 0, white)
+	ass.Equal(240.0, alphaSum(img))
+	ass.Equal(white, img.RGBAAt(2, 2))
+	img = image.NewRGBA(image.Rect(0, 0, 24, 16))
+	frostutil.FillRoundedRect(img, 2, 2, 20, 12, 100, white)
+	ass.InDelta(8*12+math.Pi*36, alphaSum(img), 0.3)
+
+	// the outline follows the corners, and leaves the inside empty
+	img = image.NewRGBA(image.Rect(0, 0, 24, 16))
+	frostutil.StrokeRoundedRect(img, 2, 2, 20, 12, 4, 2, white)
+	outer := 22*14 - (4-math.Pi)*25
+	inner := 18*10 - (4-math.Pi)*9
+	ass.InDelta(outer-inner, alphaSum(img), 0.3)
+	ass.Equal(white, img.RGBAAt(12, 1))
+	ass.Equal(white, img.RGBAAt(12, 2))
+	ass.Equal(color.RGBA{}, img.RGBAAt(12, 3))
+	ass.Equal(color.RGBA{}, img.RGBAAt(12, 8))
+}
@@ -0,0 +1,229 @@
+package frostutil
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// DumpFormat says how Recorder.Dump saves the recorded frames.
+type DumpFormat int
+
+const (
+	DumpAPNG     DumpFormat = iota // An animated PNG, written with SaveAPNG.
+	DumpGIF                        // An animated GIF, written with SaveGIF.
+	DumpSequence                   // One numbered image file per frame, in RecorderOptions.Format.
+	NumDumpFormats
+)
+
+// RecorderOptions controls a Recorder.
+type RecorderOptions struct {
+	Dir    string      // The folder files are written to, which is created if it doesn't exist. If it's empty, the current directory is used.
+	Prefix string      // The start of each file's name, which is followed by a timestamp. If it's empty, "screenshot" is used.
+	Format ImageFormat // The format screenshots (and DumpSequence frames) are saved in. If it's ImageFormatUnknown, PNG is used.
+	// Pressing any of ScreenshotKeys takes a screenshot, and pressing any of DumpKeys dumps the recorded frames.
+	// If they're nil, ebiten.KeyF12 and ebiten.KeyF9 are used. To have no hotkey, use an empty slice that isn't nil.
+	ScreenshotKeys, DumpKeys []ebiten.Key
+	// Frames is how many of the most recent frames are kept for Dump. They're kept as *ebiten.Images, so recording costs a copy on the GPU
+	// each frame, and pixels are only read back when they're dumped. If it's 0, frames aren't recorded.
+	Frames     int
+	DumpFormat DumpFormat
+	GIF        *GIFOptions // The options for DumpGIF. May be nil.
+	// If OnSaved isn't nil, it's called after each file is written, with the error if writing it failed.
+	// It's called from the goroutine that wrote the file, not the game loop.
+	OnSaved func(path string, err error)
+}
+
+// Recorder is a drop-in component for an ebiten.Game that takes screenshots when a hotkey is pressed, and can keep the last few frames,
+// to dump as an animation or a sequence of images when a bug shows up. Call Update at the start of the game's Update method, and Draw at
+// the end of its Draw method, with the screen. Files are named with the prefix and a timestamp (passed through SafeRuneForFilenames), and
+// written in background goroutines, so that encoding and writing them doesn't make the game hitch. Reading the pixels back from the GPU
+// (with NewImageFromEImage) still happens in Draw, since it has to happen in the game loop.
+type Recorder struct {
+	opts     RecorderOptions
+	keysDown map[ebiten.Key]bool
+
+	screenshotRequested, dumpRequested bool
+
+	frames      []*ebiten.Image // the ring buffer of recorded frames
+	times       []time.Time     // when each frame was recorded
+	next, count int             // where the next frame goes, and how many frames there are
+	size        image.Point     // the size of the recorded frames
+
+	lastStamp  string // the last timestamp used in a file name, and how many times it's been used, to keep names unique
+	stampCount int
+	pending    sync.WaitGroup
+}
+
+// NewRecorder creates a Recorder with the given options.
+func NewRecorder(opts RecorderOptions) *Recorder {
+	if opts.Prefix == "" {
+		opts.Prefix = "screenshot"
+	}
+	if opts.Format <= ImageFormatUnknown || opts.Format >= NumImageFormats || imageCodecs[opts.Format].encode == nil {
+		opts.Format = ImageFormatPNG
+	}
+	if opts.ScreenshotKeys == nil {
+		opts.ScreenshotKeys = []ebiten.Key{ebiten.KeyF12}
+	}
+	if opts.DumpKeys == nil {
+		opts.DumpKeys = []ebiten.Key{ebiten.KeyF9}
+	}
+	opts.Frames = Max(opts.Frames, 0)
+	return &Recorder{
+		opts:     opts,
+		keysDown: map[ebiten.Key]bool{},
+		frames:   make([]*ebiten.Image, opts.Frames),
+		times:    make([]time.Time, opts.Frames),
+	}
+}
+
+// Update checks the hotkeys. It should be called from the game's Update method.
+func (rec *Recorder) Update() {
+	if rec.justPressed(rec.opts.ScreenshotKeys) {
+		rec.screenshotRequested = true
+	}
+	if rec.justPressed(rec.opts.DumpKeys) {
+		rec.dumpRequested = true
+	}
+}
+
+// justPressed reports whether any of keys went down since the last time it was called.
+func (rec *Recorder) justPressed(keys []ebiten.Key) (pressed bool) {
+	for _, key := range keys {
+		down := ebiten.IsKeyPressed(key)
+		if down && !rec.keysDown[key] {
+			pressed = true
+		}
+		rec.keysDown[key] = down
+	}
+	return
+}
+
+// Screenshot takes a screenshot in the next call to Draw, as if the screenshot hotkey had been pressed.
+func (rec *Recorder) Screenshot() {
+	rec.screenshotRequested = true
+}
+
+// Dump saves the recorded frames in the next call to Draw, as if the dump hotkey had been pressed. The frame being drawn then is included.
+// If no frames are being recorded, it does nothing.
+func (rec *Recorder) Dump() {
+	rec.dumpRequested = true
+}
+
+// RecordedFrames returns how many frames are being kept for Dump, which is at most RecorderOptions.Frames.
+func (rec *Recorder) RecordedFrames() int {
+	return rec.count
+}
+
+// Wait waits for all the files that are being written to be finished. Call it before the game exits, so that none are cut off.
+func (rec *Recorder) Wait() {
+	rec.pending.Wait()
+}
+
+// Draw records the screen, if frames are being recorded, and takes a screenshot or dumps the recorded frames, if that's been requested.
+// It should be called at the end of the game's Draw method, once the screen has been drawn.
+func (rec *Recorder) Draw(screen *ebiten.Image) {
+	now := time.Now()
+	if rec.opts.Frames > 0 {
+		rec.record(screen, now)
+	}
+	if rec.screenshotRequested {
+		rec.screenshotRequested = false
+		img := NewImageFromEImage(screen)
+		rec.save(rec.filename(now)+imageCodecs[rec.opts.Format].extensions[0], func(path string) error { return SaveImage(path, img) })
+	}
+	if rec.dumpRequested {
+		rec.dumpRequested = false
+		rec.dump(now)
+	}
+}
+
+// record copies screen into the ring buffer.
+func (rec *Recorder) record(screen *ebiten.Image, now time.Time) {
+	size := screen.Bounds().Size()
+	if rec.count > 0 && size != rec.size {
+		// the screen changed size, so the old frames can't go in the same animation as the new ones
+		for i, frame := range rec.frames {
+			if frame != nil {
+				frame.Deallocate()
+			}
+			rec.frames[i] = nil
+		}
+		rec.next, rec.count = 0, 0
+	}
+	rec.size = size
+	if rec.frames[rec.next] == nil {
+		rec.frames[rec.next] = ebiten.NewImage(size.X, size.Y)
+	}
+	rec.frames[rec.next].DrawImage(screen, &ebiten.DrawImageOptions{Blend: ebiten.BlendCopy})
+	rec.times[rec.next] = now
+	rec.next = (rec.next + 1) % len(rec.frames)
+	rec.count = Min(rec.count+1, len(rec.frames))
+}
+
+// dump reads back the recorded frames, from oldest to newest, and saves them.
+func (rec *Recorder) dump(now time.Time) {
+	if rec.count == 0 {
+		return
+	}
+	frames := make([]AnimationFrame, rec.count)
+	start := (rec.next - rec.count + len(rec.frames)) % len(rec.frames)
+	for i := range frames {
+		idx := (start + i) % len(rec.frames)
+		frames[i].Image = NewImageFromEImage(rec.frames[idx])
+		if i > 0 {
+			frames[i-1].Delay = rec.times[idx].Sub(rec.times[(idx-1+len(rec.frames))%len(rec.frames)])
+		}
+	}
+	// the last frame has nothing after it, so it gets the same delay as the one before it
+	if len(frames) > 1 {
+		frames[len(frames)-1].Delay = frames[len(frames)-2].Delay
+	}
+	name := rec.filename(now)
+	switch rec.opts.DumpFormat {
+	case DumpGIF:
+		rec.save(name+".gif", func(path string) error { return SaveGIF(path, frames, rec.opts.GIF) })
+	case DumpSequence:
+		ext := imageCodecs[rec.opts.Format].extensions[0]
+		for i, frame := range frames {
+			img := frame.Image
+			rec.save(fmt.Sprintf("%v_%04d%v", name, i+1, ext), func(path string) error { return SaveImage(path, img) })
+		}
+	default:
+		rec.save(name+".png", func(path string) error { return SaveAPNG(path, frames, nil) })
+	}
+}
+
+// filename returns the path of a new file (without its extension) made from the prefix and the timestamp now.
+func (rec *Recorder) filename(now time.Time) string {
+	stamp := now.Format("2006-01-02_15-04-05.000")
+	if stamp == rec.lastStamp {
+		rec.stampCount++
+		stamp = fmt.Sprintf("%v_%v", stamp, rec.stampCount)
+	} else {
+		rec.lastStamp, rec.stampCount = stamp, 1
+	}
+	return filepath.Join(rec.opts.Dir, strings.Map(SafeRuneForFilenames, rec.opts.Prefix+"_"+stamp))
+}
+
+// save calls write with path in a new goroutine, after making sure the folder exists, and then calls OnSaved.
+func (rec *Recorder) save(path string, write func(path string) error) {
+	rec.pending.Add(1)
+	go func() {
+		defer rec.pending.Done()
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = write(path)
+		}
+		if rec.opts.OnSaved != nil {
+			rec.opts.OnSaved(path, err)
+		}
+	}()
+}
@@ -0,0 +1,128 @@
+package frostutil_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Recorder(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_Recorder)
+}
+
+func test_Recorder(t *testing.T) {
+	ass := assert.New(t)
+	dir := filepath.Join(t.TempDir(), "shots")
+	var mutex sync.Mutex
+	var saved []string
+	opts := frostutil.RecorderOptions{
+		Dir:    dir,
+		Prefix: "bug#12 $report",
+		Frames: 3,
+		OnSaved: func(path string, err error) {
+			assert.NoError(t, err)
+			mutex.Lock()
+			saved = append(saved, path)
+			mutex.Unlock()
+		},
+	}
+	rec := frostutil.NewRecorder(opts)
+	screen := ebiten.NewImage(6, 4)
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {255, 255, 0, 255}, {0, 255, 255, 255}}
+
+	// no hotkeys are pressed, so nothing happens until something is asked for
+	rec.Update()
+	screen.Fill(colors[0])
+	rec.Draw(screen)
+	rec.Wait()
+	ass.Empty(saved)
+	ass.Equal(1, rec.RecordedFrames())
+
+	rec.Screenshot()
+	rec.Draw(screen)
+	rec.Wait()
+	require.Len(t, saved, 1)
+	// symbols are taken out of the name, which starts with the prefix and ends with the timestamp
+	name := filepath.Base(saved[0])
+	ass.True(strings.HasPrefix(name, "bug#12 report_"), name)
+	ass.True(strings.HasSuffix(name, ".png"), name)
+	img, _, err := frostutil.LoadImage(saved[0])
+	require.NoError(t, err)
+	ass.Equal(image.Rect(0, 0, 6, 4), img.Bounds())
+	ass.Equal(color.NRGBA{255, 0, 0, 255}, color.NRGBAModel.Convert(img.At(5, 3)))
+
+	// only the last three frames are kept
+	for _, c := range colors[1:] {
+		screen.Fill(c)
+		rec.Draw(screen)
+	}
+	ass.Equal(3, rec.RecordedFrames())
+	saved = nil
+	rec.Dump()
+	screen.Fill(color.RGBA{255, 255, 255, 255})
+	rec.Draw(screen)
+	rec.Wait()
+	require.Len(t, saved, 1)
+	data, err := os.ReadFile(saved[0])
+	require.NoError(t, err)
+	frames, _, _ := decodeAPNGFrames(t, data)
+	require.Len(t, frames, 3)
+	ass.Equal(color.NRGBA{255, 255, 0, 255}, frames[0].At(0, 0))
+	ass.Equal(color.NRGBA{0, 255, 255, 255}, frames[1].At(0, 0))
+	ass.Equal(color.NRGBA{255, 255, 255, 255}, frames[2].At(0, 0))
+
+	// as a GIF, or a sequence of files
+	opts.DumpFormat = frostutil.DumpGIF
+	rec = frostutil.NewRecorder(opts)
+	saved = nil
+	for _, c := range colors[:2] {
+		screen.Fill(c)
+		rec.Draw(screen)
+	}
+	rec.Dump()
+	rec.Draw(screen)
+	rec.Wait()
+	require.Len(t, saved, 1)
+	ass.True(strings.HasSuffix(saved[0], ".gif"))
+	data, err = os.ReadFile(saved[0])
+	require.NoError(t, err)
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	ass.Len(anim.Image, 3)
+
+	opts.DumpFormat = frostutil.DumpSequence
+	opts.Format = frostutil.ImageFormatQOI
+	rec = frostutil.NewRecorder(opts)
+	saved = nil
+	for _, c := range colors[:2] {
+		screen.Fill(c)
+		rec.Draw(screen)
+	}
+	rec.Dump()
+	rec.Draw(screen)
+	rec.Wait()
+	require.Len(t, saved, 3)
+	sort.Strings(saved)
+	for i, path := range saved {
+		ass.True(strings.HasSuffix(path, []string{"_0001.qoi", "_0002.qoi", "_0003.qoi"}[i]), path)
+	}
+	img, _, err = frostutil.LoadImage(saved[0])
+	require.NoError(t, err)
+	ass.Equal(color.NRGBA{255, 0, 0, 255}, color.NRGBAModel.Convert(img.At(0, 0)))
+
+	// if the screen changes size, the old frames are dropped
+	rec.Draw(ebiten.NewImage(3, 3))
+	ass.Equal(1, rec.RecordedFrames())
+}
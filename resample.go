@@ -0,0 +1,370 @@
+package frostutil
+
+import (
+	"image"
+	"math"
+)
+
+// This file implements resizing, rotating by multiples of 90 degrees, flipping, and cropping *image.RGBA and *image.NRGBA images on the CPU.
+// Resizing filters in alpha-premultiplied space, so transparent pixels don't bleed their (usually black) color into their neighbors,
+// which is what gives sprites dark halos when they're filtered with straight alpha. Optionally, it filters in linear light instead of sRGB.
+
+// ResampleFilter selects the filter Resize uses.
+type ResampleFilter int
+
+const (
+	FilterNearest  ResampleFilter = iota // Nearest neighbor. Fast and blocky, and the only filter which never makes new colors.
+	FilterBilinear                       // Linear interpolation (a triangle filter).
+	FilterBicubic                        // Catmull-Rom cubic interpolation, which is sharper than bilinear.
+	FilterLanczos3                       // Lanczos with 3 lobes. The sharpest, but it can ring around hard edges.
+	FilterBox                            // Averages every source pixel covered by each destination pixel. Good for shrinking by integer factors.
+	NumResampleFilters
+)
+
+// ResizeOptions are the options for Resize.
+type ResizeOptions struct {
+	Filter      ResampleFilter
+	LinearLight bool // If true, the colors are converted from sRGB to linear light before filtering, and back afterwards.
+}
+
+// resampleKernel is a filter function along with how far it extends from its center (its support).
+type resampleKernel struct {
+	support float64
+	f       func(x float64) float64
+}
+
+var resampleKernels = [NumResampleFilters]resampleKernel{
+	FilterNearest: {0, nil}, // handled separately
+	FilterBilinear: {1, func(x float64) float64 {
+		x = math.Abs(x)
+		if x < 1 {
+			return 1 - x
+		}
+		return 0
+	}},
+	FilterBicubic: {2, func(x float64) float64 {
+		// Catmull-Rom, which is the cubic with B=0 and C=0.5
+		x = math.Abs(x)
+		if x < 1 {
+			return (1.5*x-2.5)*x*x + 1
+		} else if x < 2 {
+			return ((-0.5*x+2.5)*x-4)*x + 2
+		}
+		return 0
+	}},
+	FilterLanczos3: {3, func(x float64) float64 {
+		if x == 0 {
+			return 1
+		}
+		if x > -3 && x < 3 {
+			px := math.Pi * x
+			return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+		}
+		return 0
+	}},
+	FilterBox: {0.5, func(x float64) float64 {
+		if x >= -0.5 && x < 0.5 {
+			return 1
+		}
+		return 0
+	}},
+}
+
+// resampleWeights holds the source pixels and weights which contribute to one destination pixel along one axis.
+type resampleWeights struct {
+	start   int       // the first source pixel
+	weights []float32 // the weight of each source pixel from start onward
+}
+
+// computeResampleWeights works out which source pixels contribute to each destination pixel along one axis, and how much.
+// When shrinking, the kernel is stretched to cover all the source pixels that end up in each destination pixel.
+// Source pixels past the edges are clamped to the edge pixels.
+func computeResampleWeights(dstSize, srcSize int, kernel resampleKernel) []resampleWeights {
+	scale := float64(srcSize) / float64(dstSize)
+	filterScale := math.Max(scale, 1)
+	support := kernel.support * filterScale
+	out := make([]resampleWeights, dstSize)
+	for i := range out {
+		center := (float64(i)+0.5)*scale - 0.5
+		left := int(math.Ceil(center - support))
+		right := int(math.Floor(center + support))
+		if kernel.support == 0.5 {
+			// The box filter is only 1 on [-0.5, 0.5), so we'd pick up an extra pixel with a weight of zero on the right.
+			right = int(math.Ceil(center+support)) - 1
+		}
+		lo, hi := Max(left, 0), Min(right, srcSize-1)
+		weights := make([]float32, hi-lo+1)
+		sum := 0.0
+		for j := left; j <= right; j++ {
+			w := kernel.f((float64(j) - center) / filterScale)
+			if w == 0 {
+				continue
+			}
+			weights[Min(Max(j, lo), hi)-lo] += float32(w)
+			sum += w
+		}
+		if sum != 0 {
+			for k := range weights {
+				weights[k] /= float32(sum)
+			}
+		}
+		out[i] = resampleWeights{start: lo, weights: weights}
+	}
+	return out
+}
+
+// srgbToLinearTable converts 8-bit sRGB components to linear light from 0 to 1.
+var srgbToLinearTable = func() (table [256]float32) {
+	for i := range table {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			table[i] = float32(c / 12.92)
+		} else {
+			table[i] = float32(math.Pow((c+0.055)/1.055, 2.4))
+		}
+	}
+	return
+}()
+
+// linearToSRGBTableSize is how many entries linearToSRGBTable has. It needs to be much bigger than 256, since linear light crowds the dark colors together.
+const linearToSRGBTableSize = 4096
+
+// linearToSRGBTable converts linear light (from 0 to 1, in linearToSRGBTableSize steps) back to 8-bit sRGB components.
+var linearToSRGBTable = func() (table [linearToSRGBTableSize]byte) {
+	for i := range table {
+		c := float64(i) / (linearToSRGBTableSize - 1)
+		if c <= 0.0031308 {
+			c *= 12.92
+		} else {
+			c = 1.055*math.Pow(c, 1/2.4) - 0.055
+		}
+		table[i] = byte(math.Round(c * 255))
+	}
+	return
+}()
+
+// linearToSRGB converts a linear light value from 0 to 1 to an 8-bit sRGB component.
+func linearToSRGB(c float32) byte {
+	return linearToSRGBTable[int(clampUnit(c)*(linearToSRGBTableSize-1)+0.5)]
+}
+
+// clampUnit clamps x to the range [0, 1].
+func clampUnit(x float32) float32 {
+	if x < 0 {
+		return 0
+	} else if x > 1 {
+		return 1
+	}
+	return x
+}
+
+// toPremultipliedFloats converts img's pixels to alpha-premultiplied float32s from 0 to 1, 4 per pixel, with no padding between rows.
+// NRGBA pixels are premultiplied with MultiplyAlphaBytes, unless linear is true, in which case the straight colors (from NRGBA pixels,
+// or from RGBA pixels unmultiplied with UnmultiplyAlphaBytes) are converted to linear light before being multiplied by alpha.
+func toPremultipliedFloats[T PixImage](img T, linear bool) (out []float32, width, height int) {
+	pix, stride, bounds, premultiplied := pixBuffer(img)
+	width, height = bounds.Dx(), bounds.Dy()
+	out = make([]float32, width*height*4)
+	o := 0
+	for y := 0; y < height; y++ {
+		idx := y * stride
+		for x := 0; x < width; x++ {
+			r, g, b, a := pix[idx], pix[idx+1], pix[idx+2], pix[idx+3]
+			if linear {
+				if premultiplied {
+					r, g, b, a = UnmultiplyAlphaBytes(r, g, b, a)
+				}
+				fa := float32(a) / 255
+				out[o] = srgbToLinearTable[r] * fa
+				out[o+1] = srgbToLinearTable[g] * fa
+				out[o+2] = srgbToLinearTable[b] * fa
+				out[o+3] = fa
+			} else {
+				if !premultiplied {
+					r, g, b, a = MultiplyAlphaBytes(r, g, b, a)
+				}
+				out[o] = float32(r) / 255
+				out[o+1] = float32(g) / 255
+				out[o+2] = float32(b) / 255
+				out[o+3] = float32(a) / 255
+			}
+			idx += 4
+			o += 4
+		}
+	}
+	return
+}
+
+// fromPremultipliedFloats is the reverse of toPremultipliedFloats: it converts alpha-premultiplied float32s to a new image of type T.
+// For RGBA output in sRGB, the premultiplied components are stored directly; for NRGBA output they are unmultiplied with UnmultiplyAlphaBytes.
+// In linear light, the straight colors are converted back to sRGB, and multiplied by alpha with MultiplyAlphaBytes for RGBA output.
+func fromPremultipliedFloats[T PixImage](in []float32, width, height int, linear bool) T {
+	img := newPixImage[T](image.Rect(0, 0, width, height))
+	pix, stride, _, premultiplied := pixBuffer(img)
+	i := 0
+	for y := 0; y < height; y++ {
+		idx := y * stride
+		for x := 0; x < width; x++ {
+			fa := clampUnit(in[i+3])
+			a := byte(fa*255 + 0.5)
+			var r, g, b byte
+			if a == 0 {
+				// fully transparent, so there's no color left to recover
+			} else if linear {
+				r = linearToSRGB(in[i] / fa)
+				g = linearToSRGB(in[i+1] / fa)
+				b = linearToSRGB(in[i+2] / fa)
+				if premultiplied {
+					r, g, b, a = MultiplyAlphaBytes(r, g, b, a)
+				}
+			} else {
+				// premultiplied components can't be greater than alpha
+				r = Min(byte(clampUnit(in[i])*255+0.5), a)
+				g = Min(byte(clampUnit(in[i+1])*255+0.5), a)
+				b = Min(byte(clampUnit(in[i+2])*255+0.5), a)
+				if !premultiplied {
+					r, g, b, a = UnmultiplyAlphaBytes(r, g, b, a)
+				}
+			}
+			pix[idx], pix[idx+1], pix[idx+2], pix[idx+3] = r, g, b, a
+			idx += 4
+			i += 4
+		}
+	}
+	return img
+}
+
+// Resize returns a copy of img resized to width x height, with its top-left corner at (0, 0).
+// Filtering happens in alpha-premultiplied space (see toPremultipliedFloats), in two passes: first horizontally, then vertically.
+// FilterNearest just copies pixels, so it keeps the exact colors (including the colors of fully transparent NRGBA pixels).
+// If width or height is <= 0, or img is empty, it returns an empty image.
+func Resize[T PixImage](img T, width, height int, opts ResizeOptions) T {
+	_, _, bounds, _ := pixBuffer(img)
+	if width <= 0 || height <= 0 || bounds.Empty() {
+		return newPixImage[T](image.Rectangle{})
+	}
+	if opts.Filter == FilterNearest || opts.Filter < 0 || opts.Filter >= NumResampleFilters {
+		return resizeNearest(img, width, height)
+	}
+	in, srcW, srcH := toPremultipliedFloats(img, opts.LinearLight)
+	kernel := resampleKernels[opts.Filter]
+
+	// horizontal pass, from srcW x srcH to width x srcH
+	xWeights := computeResampleWeights(width, srcW, kernel)
+	tmp := make([]float32, width*srcH*4)
+	for y := 0; y < srcH; y++ {
+		row := in[y*srcW*4:]
+		for x, xw := range xWeights {
+			var r, g, b, a float32
+			for k, w := range xw.weights {
+				idx := (xw.start + k) * 4
+				r += row[idx] * w
+				g += row[idx+1] * w
+				b += row[idx+2] * w
+				a += row[idx+3] * w
+			}
+			o := (y*width + x) * 4
+			tmp[o], tmp[o+1], tmp[o+2], tmp[o+3] = r, g, b, a
+		}
+	}
+
+	// vertical pass, from width x srcH to width x height
+	yWeights := computeResampleWeights(height, srcH, kernel)
+	out := make([]float32, width*height*4)
+	for y, yw := range yWeights {
+		for x := 0; x < width; x++ {
+			var r, g, b, a float32
+			for k, w := range yw.weights {
+				idx := ((yw.start+k)*width + x) * 4
+				r += tmp[idx] * w
+				g += tmp[idx+1] * w
+				b += tmp[idx+2] * w
+				a += tmp[idx+3] * w
+			}
+			o := (y*width + x) * 4
+			out[o], out[o+1], out[o+2], out[o+3] = r, g, b, a
+		}
+	}
+	return fromPremultipliedFloats[T](out, width, height, opts.LinearLight)
+}
+
+// resizeNearest implements Resize for FilterNearest, picking the source pixel under the center of each destination pixel.
+func resizeNearest[T PixImage](img T, width, height int) T {
+	pix, stride, bounds, _ := pixBuffer(img)
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	out := newPixImage[T](image.Rect(0, 0, width, height))
+	oPix, oStride, _, _ := pixBuffer(out)
+	for y := 0; y < height; y++ {
+		sy := Min((2*y+1)*srcH/(2*height), srcH-1)
+		oIdx := y * oStride
+		for x := 0; x < width; x++ {
+			sx := Min((2*x+1)*srcW/(2*width), srcW-1)
+			iIdx := sy*stride + sx*4
+			copy(oPix[oIdx:oIdx+4], pix[iIdx:iIdx+4])
+			oIdx += 4
+		}
+	}
+	return out
+}
+
+// transformPixels creates a new image of the given size, and fills it by copying each of its pixels from the source pixel given by
+// src(x, y), where (x, y) are coordinates in the new image and the returned coordinates are relative to the source image's top-left corner.
+func transformPixels[T PixImage](img T, width, height int, src func(x, y int) (int, int)) T {
+	pix, stride, _, _ := pixBuffer(img)
+	out := newPixImage[T](image.Rect(0, 0, width, height))
+	oPix, oStride, _, _ := pixBuffer(out)
+	for y := 0; y < height; y++ {
+		oIdx := y * oStride
+		for x := 0; x < width; x++ {
+			sx, sy := src(x, y)
+			iIdx := sy*stride + sx*4
+			copy(oPix[oIdx:oIdx+4], pix[iIdx:iIdx+4])
+			oIdx += 4
+		}
+	}
+	return out
+}
+
+// Rotate90 returns a copy of img rotated 90 degrees clockwise, with its top-left corner at (0, 0).
+func Rotate90[T PixImage](img T) T {
+	_, _, bounds, _ := pixBuffer(img)
+	w, h := bounds.Dx(), bounds.Dy()
+	return transformPixels(img, h, w, func(x, y int) (int, int) { return y, h - 1 - x })
+}
+
+// Rotate180 returns a copy of img rotated 180 degrees, with its top-left corner at (0, 0).
+func Rotate180[T PixImage](img T) T {
+	_, _, bounds, _ := pixBuffer(img)
+	w, h := bounds.Dx(), bounds.Dy()
+	return transformPixels(img, w, h, func(x, y int) (int, int) { return w - 1 - x, h - 1 - y })
+}
+
+// Rotate270 returns a copy of img rotated 270 degrees clockwise (90 degrees counterclockwise), with its top-left corner at (0, 0).
+func Rotate270[T PixImage](img T) T {
+	_, _, bounds, _ := pixBuffer(img)
+	w, h := bounds.Dx(), bounds.Dy()
+	return transformPixels(img, h, w, func(x, y int) (int, int) { return w - 1 - y, x })
+}
+
+// FlipHorizontal returns a copy of img mirrored left to right, with its top-left corner at (0, 0).
+func FlipHorizontal[T PixImage](img T) T {
+	_, _, bounds, _ := pixBuffer(img)
+	w, h := bounds.Dx(), bounds.Dy()
+	return transformPixels(img, w, h, func(x, y int) (int, int) { return w - 1 - x, y })
+}
+
+// FlipVertical returns a copy of img mirrored top to bottom, with its top-left corner at (0, 0).
+func FlipVertical[T PixImage](img T) T {
+	_, _, bounds, _ := pixBuffer(img)
+	w, h := bounds.Dx(), bounds.Dy()
+	return transformPixels(img, w, h, func(x, y int) (int, int) { return x, h - 1 - y })
+}
+
+// Crop returns a copy of the part of img inside rect (which is in img's coordinates, and is clipped to img's bounds),
+// with its top-left corner at (0, 0). Unlike SubImage, the result doesn't share pixels with img.
+func Crop[T PixImage](img T, rect image.Rectangle) T {
+	_, _, bounds, _ := pixBuffer(img)
+	rect = rect.Intersect(bounds)
+	offset := rect.Min.Sub(bounds.Min)
+	return transformPixels(img, rect.Dx(), rect.Dy(), func(x, y int) (int, int) { return x + offset.X, y + offset.Y })
+}
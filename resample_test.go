@@ -0,0 +1,136 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var allResampleFilters = []frostutil.ResampleFilter{frostutil.FilterNearest, frostutil.FilterBilinear, frostutil.FilterBicubic, frostutil.FilterLanczos3, frostutil.FilterBox}
+
+func Test_ResizeIdentity(t *testing.T) {
+	ass := assert.New(t)
+	src := image.NewRGBA(image.Rect(0, 0, 9, 7))
+	for i := range src.Pix {
+		src.Pix[i] = byte(i * 7)
+	}
+	// make it a valid premultiplied image
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i+3] = 255
+	}
+	for _, filter := range allResampleFilters {
+		out := frostutil.Resize(src, 9, 7, frostutil.ResizeOptions{Filter: filter})
+		ass.Equal(src.Pix, out.Pix, "filter %v", filter)
+	}
+}
+
+func Test_ResizeNoDarkHalo(t *testing.T) {
+	ass := assert.New(t)
+	// a red square on a transparent black background
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 2; y < 6; y++ {
+		for x := 2; x < 6; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	for _, filter := range allResampleFilters[1:] {
+		for _, linear := range []bool{false, true} {
+			out := frostutil.Resize(src, 13, 13, frostutil.ResizeOptions{Filter: filter, LinearLight: linear})
+			ass.Equal(image.Rect(0, 0, 13, 13), out.Bounds())
+			for i := 0; i < len(out.Pix); i += 4 {
+				if out.Pix[i+3] > 0 {
+					// Every visible pixel has to stay pure red. With straight alpha, the edges would get darker.
+					ass.Equal(color.NRGBA{R: 255, A: out.Pix[i+3]}, color.NRGBA{out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3]}, "filter %v, linear %v", filter, linear)
+				}
+			}
+		}
+	}
+}
+
+func Test_ResizeShrink(t *testing.T) {
+	ass := assert.New(t)
+	// a checkerboard of black and white averages out to gray
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			v := byte(0)
+			if (x+y)%2 == 0 {
+				v = 255
+			}
+			src.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	out := frostutil.Resize(src, 2, 2, frostutil.ResizeOptions{Filter: frostutil.FilterBox})
+	ass.InDelta(128, int(out.Pix[0]), 1)
+	// in linear light, half white is much brighter than 128
+	out = frostutil.Resize(src, 2, 2, frostutil.ResizeOptions{Filter: frostutil.FilterBox, LinearLight: true})
+	ass.InDelta(188, int(out.Pix[0]), 1)
+
+	ass.Equal(image.Rectangle{}, frostutil.Resize(src, 0, 5, frostutil.ResizeOptions{}).Bounds())
+}
+
+// NRGBA output is unmultiplied from the filtered floats before it's rounded, rather than being rounded to premultiplied bytes first
+// and unmultiplied with UnmultiplyAlphaBytes, which would multiply the rounding error by 255/alpha for translucent pixels.
+func Test_ResizeNRGBATranslucent(t *testing.T) {
+	ass := assert.New(t)
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	// the premultiplied components are {51, 0, 0, 64} and {0, 0, 12, 16}, so the average is {25.5, 0, 6, 40}
+	src.SetNRGBA(0, 0, color.NRGBA{204, 0, 0, 0x40})
+	src.SetNRGBA(1, 0, color.NRGBA{0, 0, 200, 0x10})
+	out := frostutil.Resize(src, 1, 1, frostutil.ResizeOptions{Filter: frostutil.FilterBox})
+	// the average is rounded to the premultiplied bytes {26, 0, 6, 40} and then unmultiplied with UnmultiplyAlphaBytes
+	ass.Equal(color.NRGBA{165, 0, 38, 40}, out.NRGBAAt(0, 0))
+	// the RGBA result is the same rounded average
+	rSrc := &image.RGBA{Pix: []byte{51, 0, 0, 64, 0, 0, 12, 16}, Stride: 8, Rect: image.Rect(0, 0, 2, 1)}
+	rOut := frostutil.Resize(rSrc, 1, 1, frostutil.ResizeOptions{Filter: frostutil.FilterBox})
+	ass.Equal(color.RGBA{26, 0, 6, 40}, rOut.RGBAAt(0, 0))
+}
+
+func Test_ResizeNearest(t *testing.T) {
+	ass := assert.New(t)
+	src := makeSprite(3, 4, 2)
+	// colors of fully transparent pixels are kept
+	src.SetNRGBA(1, 1, color.NRGBA{R: 9, G: 8, B: 7, A: 0})
+	out := frostutil.Resize(src, 8, 4, frostutil.ResizeOptions{Filter: frostutil.FilterNearest})
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			ass.Equal(src.NRGBAAt(x/2, y/2), out.NRGBAAt(x, y))
+		}
+	}
+	out = frostutil.Resize(src.SubImage(image.Rect(2, 0, 4, 2)).(*image.NRGBA), 1, 1, frostutil.ResizeOptions{})
+	ass.Equal(src.NRGBAAt(3, 1), out.NRGBAAt(0, 0))
+}
+
+func Test_RotateFlipCrop(t *testing.T) {
+	ass := assert.New(t)
+	src := makeSprite(1, 5, 3)
+
+	r90 := frostutil.Rotate90(src)
+	ass.Equal(image.Rect(0, 0, 3, 5), r90.Bounds())
+	// the bottom-left corner ends up at the top left
+	ass.Equal(src.NRGBAAt(0, 2), r90.NRGBAAt(0, 0))
+	ass.Equal(src.NRGBAAt(0, 0), r90.NRGBAAt(2, 0))
+	ass.Equal(src.Pix, frostutil.Rotate90(frostutil.Rotate90(frostutil.Rotate90(r90))).Pix)
+	ass.Equal(src.Pix, frostutil.Rotate270(r90).Pix)
+	ass.Equal(frostutil.Rotate180(src).Pix, frostutil.Rotate90(r90).Pix)
+	ass.Equal(frostutil.Rotate180(src).Pix, frostutil.FlipVertical(frostutil.FlipHorizontal(src)).Pix)
+
+	flipped := frostutil.FlipHorizontal(src)
+	ass.Equal(src.NRGBAAt(4, 1), flipped.NRGBAAt(0, 1))
+	flipped = frostutil.FlipVertical(src)
+	ass.Equal(src.NRGBAAt(1, 2), flipped.NRGBAAt(1, 0))
+
+	sub := src.SubImage(image.Rect(1, 1, 5, 3)).(*image.NRGBA)
+	cropped := frostutil.Crop(sub, image.Rect(2, 0, 10, 2))
+	ass.Equal(image.Rect(0, 0, 3, 1), cropped.Bounds())
+	ass.Equal(src.NRGBAAt(2, 1), cropped.NRGBAAt(0, 0))
+	cropped.SetNRGBA(0, 0, color.NRGBA{})
+	ass.NotEqual(color.NRGBA{}, src.NRGBAAt(2, 1))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	rgba.SetRGBA(1, 0, color.RGBA{R: 1, A: 1})
+	ass.Equal(color.RGBA{R: 1, A: 1}, frostutil.FlipHorizontal(rgba).RGBAAt(0, 0))
+}
@@ -0,0 +1,179 @@
+package frostutil
+
+import (
+	"image"
+	"math"
+)
+
+// SDFOptions controls NewSDF and NewSDF_NRGBA.
+type SDFOptions struct {
+	AlphaThreshold byte // Pixels with alpha greater than this are inside the shape.
+	// Spread is the distance, in source image pixels, from the edge of the shape to where the field reaches 0 (outside) or 255 (inside).
+	// Bigger spreads allow wider outlines and glows, at the cost of precision. If it's <= 0, 8 is used.
+	Spread float64
+	// Width and Height are the size of the output image. Typically the source image is drawn at a high resolution and the field is made smaller.
+	// If either is <= 0, the source image's size is used for it.
+	Width, Height int
+}
+
+// defaultSDFSpread is the spread NewSDF uses if SDFOptions.Spread isn't set.
+const defaultSDFSpread = 8
+
+// NewSDF generates a signed distance field from img's alpha channel, as an *image.Gray whose top-left corner is at (0, 0).
+// Each output pixel holds the distance from its center to the edge of the shape: 128 on the edge (more precisely, 127.5), increasing towards 255
+// inside the shape and decreasing towards 0 outside it, reaching them at opts.Spread pixels away. In a shader, smoothstep around 0.5 gives crisp edges
+// at any scale, and other thresholds give outlines and glows.
+// The distances are computed exactly (with the Felzenszwalb-Huttenlocher Euclidean distance transform) at the source image's resolution, and then
+// sampled at the output size with bilinear interpolation. Everything outside img's bounds counts as outside the shape.
+func NewSDF(img image.Image, opts SDFOptions) *image.Gray {
+	values, width, height := sdfValues(img, opts)
+	out := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		copy(out.Pix[y*out.Stride:y*out.Stride+width], values[y*width:(y+1)*width])
+	}
+	return out
+}
+
+// NewSDF_NRGBA is the same as NewSDF, except it returns an opaque *image.NRGBA with the distance in the red, green, and blue components,
+// which NewEImageFromImage can convert straight from its pixel buffer. Since it's opaque, alpha premultiplication doesn't affect the values.
+func NewSDF_NRGBA(img image.Image, opts SDFOptions) *image.NRGBA {
+	values, width, height := sdfValues(img, opts)
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i, v := range values {
+		out.Pix[i*4], out.Pix[i*4+1], out.Pix[i*4+2], out.Pix[i*4+3] = v, v, v, 0xff
+	}
+	return out
+}
+
+// sdfValues computes the output of NewSDF as bytes, row by row with no padding, along with the output size.
+func sdfValues(img image.Image, opts SDFOptions) (values []byte, width, height int) {
+	alpha, srcW, srcH := alphaValues(img)
+	width, height = opts.Width, opts.Height
+	if width <= 0 {
+		width = srcW
+	}
+	if height <= 0 {
+		height = srcH
+	}
+	spread := opts.Spread
+	if spread <= 0 {
+		spread = defaultSDFSpread
+	}
+	values = make([]byte, width*height)
+	if srcW == 0 || srcH == 0 {
+		return
+	}
+
+	// Add a border of outside pixels, so that shapes touching the edges of the image still have an edge there.
+	padW, padH := srcW+2, srcH+2
+	toInside := make([]float64, padW*padH)  // squared distance to the nearest inside pixel
+	toOutside := make([]float64, padW*padH) // squared distance to the nearest outside pixel
+	for i := range toInside {
+		x, y := i%padW-1, i/padW-1
+		if x >= 0 && y >= 0 && x < srcW && y < srcH && alpha[y*srcW+x] > opts.AlphaThreshold {
+			toOutside[i] = math.Inf(1)
+		} else {
+			toInside[i] = math.Inf(1)
+		}
+	}
+	distanceTransform(toInside, padW, padH)
+	distanceTransform(toOutside, padW, padH)
+	// The edge is halfway between an inside pixel and its outside neighbor, so the distances from pixel centers to the edge are half a pixel less.
+	signed := make([]float64, padW*padH)
+	for i := range signed {
+		if toInside[i] == 0 {
+			signed[i] = math.Sqrt(toOutside[i]) - 0.5
+		} else {
+			signed[i] = 0.5 - math.Sqrt(toInside[i])
+		}
+	}
+
+	scaleX, scaleY := float64(srcW)/float64(width), float64(srcH)/float64(height)
+	for y := 0; y < height; y++ {
+		// +1 for the border
+		sy := Min(Max((float64(y)+0.5)*scaleY-0.5+1, 0), float64(padH-1))
+		y0 := Min(int(sy), padH-2)
+		fy := sy - float64(y0)
+		for x := 0; x < width; x++ {
+			sx := Min(Max((float64(x)+0.5)*scaleX-0.5+1, 0), float64(padW-1))
+			x0 := Min(int(sx), padW-2)
+			fx := sx - float64(x0)
+			i := y0*padW + x0
+			top := signed[i]*(1-fx) + signed[i+1]*fx
+			bottom := signed[i+padW]*(1-fx) + signed[i+padW+1]*fx
+			d := top*(1-fy) + bottom*fy
+			v := Min(Max(0.5+d/(2*spread), 0), 1)
+			values[y*width+x] = byte(v*0xff + 0.5)
+		}
+	}
+	return
+}
+
+// distanceTransform replaces each value in grid (which should be 0 for the pixels to measure the distance to, and +Inf elsewhere)
+// with the squared Euclidean distance to the nearest 0 pixel, using the Felzenszwalb-Huttenlocher algorithm, one dimension at a time.
+func distanceTransform(grid []float64, width, height int) {
+	n := Max(width, height)
+	f := make([]float64, n)
+	d := make([]float64, n)
+	v := make([]int, n)
+	z := make([]float64, n+1)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			f[y] = grid[y*width+x]
+		}
+		distanceTransform1D(f[:height], d[:height], v, z)
+		for y := 0; y < height; y++ {
+			grid[y*width+x] = d[y]
+		}
+	}
+	for y := 0; y < height; y++ {
+		row := grid[y*width : (y+1)*width]
+		copy(f, row)
+		distanceTransform1D(f[:width], d[:width], v, z)
+		copy(row, d[:width])
+	}
+}
+
+// distanceTransform1D computes the 1D squared distance transform of f into d: d[q] is the minimum over p of (q-p)^2 + f[p].
+// It finds the lower envelope of the parabolas rooted at each p. v and z are scratch space, with room for len(f) and len(f)+1 values.
+func distanceTransform1D(f, d []float64, v []int, z []float64) {
+	n := len(f)
+	k := -1
+	for q := 0; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		var s float64
+		for k >= 0 {
+			p := v[k]
+			s = ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*(q-p))
+			if s > z[k] {
+				break
+			}
+			k--
+		}
+		k++
+		v[k] = q
+		if k == 0 {
+			z[k] = math.Inf(-1)
+		} else {
+			z[k] = s
+		}
+		z[k+1] = math.Inf(1)
+	}
+	if k < 0 {
+		// there are no roots in this line, so everything stays infinitely far away
+		for q := range d {
+			d[q] = math.Inf(1)
+		}
+		return
+	}
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		p := v[k]
+		d[q] = float64((q-p)*(q-p)) + f[p]
+	}
+}
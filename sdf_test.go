@@ -0,0 +1,57 @@
+package frostutil_test
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectedCircleSDF returns the value NewSDF should give for a point (px, py) in the source image made by makeCircleImage(size).
+func expectedCircleSDF(px, py float64, size int, spread float64) float64 {
+	r := float64(size) / 2
+	d := r - math.Hypot(px-r, py-r)
+	return math.Min(math.Max(0.5+d/(2*spread), 0), 1) * 255
+}
+
+func Test_NewSDF(t *testing.T) {
+	ass := assert.New(t)
+	img := makeCircleImage(64)
+	sdf := frostutil.NewSDF(img, frostutil.SDFOptions{Spread: 16})
+	ass.Equal(image.Rect(0, 0, 64, 64), sdf.Bounds())
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			// a pixelated circle's edge is up to about half a pixel from the real circle's
+			if !ass.InDelta(expectedCircleSDF(float64(x)+0.5, float64(y)+0.5, 64, 16), float64(sdf.GrayAt(x, y).Y), 6, "(%v, %v)", x, y) {
+				return
+			}
+		}
+	}
+
+	// at a smaller size
+	small := frostutil.NewSDF(img, frostutil.SDFOptions{Spread: 16, Width: 16, Height: 16})
+	ass.Equal(image.Rect(0, 0, 16, 16), small.Bounds())
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			ass.InDelta(expectedCircleSDF(float64(x*4)+2, float64(y*4)+2, 64, 16), float64(small.GrayAt(x, y).Y), 6, "(%v, %v)", x, y)
+		}
+	}
+
+	nrgba := frostutil.NewSDF_NRGBA(img, frostutil.SDFOptions{Spread: 16, Width: 16, Height: 16})
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			v := small.GrayAt(x, y).Y
+			c := nrgba.NRGBAAt(x, y)
+			ass.Equal([4]byte{v, v, v, 255}, [4]byte{c.R, c.G, c.B, c.A})
+		}
+	}
+
+	// a shape that touches the edges still has an edge there, and an empty image is all outside
+	full := frostutil.NewSDF(makeMaskImage(0, 0, "###", "###", "###"), frostutil.SDFOptions{Spread: 2})
+	ass.Equal(uint8(159), full.GrayAt(0, 1).Y) // 0.5 pixels from the edge, which is 0.5 + 0.5/4 of 255
+	ass.Equal(uint8(223), full.GrayAt(1, 1).Y) // 1.5 pixels from the edge
+	empty := frostutil.NewSDF(image.NewAlpha(image.Rect(0, 0, 3, 3)), frostutil.SDFOptions{})
+	ass.Equal(make([]byte, 9), empty.Pix)
+}
@@ -0,0 +1,130 @@
+package frostutil
+
+import (
+	"image"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// GridSliceOptions describes the layout of a sprite sheet made of equally sized cells.
+type GridSliceOptions struct {
+	CellWidth, CellHeight int // The size of each cell. Both must be > 0.
+	Margin                int // Pixels between the edges of the sheet and the first row and column of cells.
+	Spacing               int // Pixels between neighboring cells.
+}
+
+// SliceGrid returns the rectangles of every complete cell in a sheet with the given bounds, row by row from the top left.
+// Cells which would extend past the right or bottom edge of the sheet are left out. If either cell dimension is <= 0, it returns nil.
+func SliceGrid(bounds image.Rectangle, opts GridSliceOptions) (rects []image.Rectangle) {
+	if opts.CellWidth <= 0 || opts.CellHeight <= 0 {
+		return nil
+	}
+	for y := bounds.Min.Y + opts.Margin; y+opts.CellHeight <= bounds.Max.Y; y += opts.CellHeight + opts.Spacing {
+		for x := bounds.Min.X + opts.Margin; x+opts.CellWidth <= bounds.Max.X; x += opts.CellWidth + opts.Spacing {
+			rects = append(rects, image.Rect(x, y, x+opts.CellWidth, y+opts.CellHeight))
+		}
+	}
+	return
+}
+
+// ComponentSliceOptions controls how SliceConnected finds sprites.
+type ComponentSliceOptions struct {
+	AlphaThreshold  byte // Pixels with alpha greater than this are part of a sprite.
+	DiagonalConnect bool // If true, pixels which only touch diagonally are connected too (8-connectivity instead of 4-connectivity).
+	MergeDistance   int  // Sprites whose bounding boxes are this many pixels apart or closer are merged, so that sprites made of separate pieces stay together.
+	MinPixels       int  // Components with fewer pixels than this are dropped as specks, before merging.
+}
+
+// SliceConnected finds the bounding boxes of the connected regions of non-transparent pixels in img, in img's coordinates.
+// The rectangles are sorted top to bottom, and then left to right.
+func SliceConnected(img image.Image, opts ComponentSliceOptions) (rects []image.Rectangle) {
+	alpha, width, height := alphaValues(img)
+	// labels holds the index+1 of the component each pixel belongs to, or 0 if it isn't solid or hasn't been visited yet.
+	labels := make([]int32, width*height)
+	var stack []int
+	var counts []int
+	for start := range alpha {
+		if alpha[start] <= opts.AlphaThreshold || labels[start] != 0 {
+			continue
+		}
+		label := int32(len(rects) + 1)
+		rect := image.Rect(start%width, start/width, start%width+1, start/width+1)
+		count := 0
+		labels[start] = label
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			idx := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			count++
+			x, y := idx%width, idx/width
+			rect = rect.Union(image.Rect(x, y, x+1, y+1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx == 0 && dy == 0) || (!opts.DiagonalConnect && dx != 0 && dy != 0) {
+						continue
+					}
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= width || ny >= height {
+						continue
+					}
+					nIdx := ny*width + nx
+					if alpha[nIdx] > opts.AlphaThreshold && labels[nIdx] == 0 {
+						labels[nIdx] = label
+						stack = append(stack, nIdx)
+					}
+				}
+			}
+		}
+		rects = append(rects, rect)
+		counts = append(counts, count)
+	}
+
+	// drop the specks
+	kept := rects[:0]
+	for i, rect := range rects {
+		if counts[i] >= opts.MinPixels {
+			kept = append(kept, rect)
+		}
+	}
+	rects = kept
+
+	if opts.MergeDistance > 0 {
+		for merged := true; merged; {
+			merged = false
+			for i := 0; i < len(rects); i++ {
+				for j := i + 1; j < len(rects); j++ {
+					// boxes MergeDistance pixels apart have to overlap once they're grown, so they're grown by one more pixel than that
+					if rects[i].Inset(-(opts.MergeDistance + 1)).Overlaps(rects[j]) {
+						rects[i] = rects[i].Union(rects[j])
+						rects = append(rects[:j], rects[j+1:]...)
+						merged = true
+						j--
+					}
+				}
+			}
+		}
+	}
+
+	offset := img.Bounds().Min
+	for i := range rects {
+		rects[i] = rects[i].Add(offset)
+	}
+	sort.Slice(rects, func(a, b int) bool {
+		if rects[a].Min.Y != rects[b].Min.Y {
+			return rects[a].Min.Y < rects[b].Min.Y
+		}
+		return rects[a].Min.X < rects[b].Min.X
+	})
+	return
+}
+
+// ESubImages returns the sub-image of eImg for each of rects, in the same order, such as those returned by SliceGrid or SliceConnected.
+// The sub-images share eImg's pixels.
+func ESubImages(eImg *ebiten.Image, rects []image.Rectangle) (subImages []*ebiten.Image) {
+	subImages = make([]*ebiten.Image, len(rects))
+	for i, rect := range rects {
+		subImages[i] = eImg.SubImage(rect).(*ebiten.Image)
+	}
+	return
+}
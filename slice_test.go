@@ -0,0 +1,111 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SliceGrid(t *testing.T) {
+	ass := assert.New(t)
+	rects := frostutil.SliceGrid(image.Rect(0, 0, 35, 24), frostutil.GridSliceOptions{CellWidth: 10, CellHeight: 10, Margin: 1, Spacing: 2})
+	// columns start at 1, 13, and 25 (25+10 = 35 fits), rows start at 1 and 13 (13+10 = 23 fits)
+	ass.Equal([]image.Rectangle{
+		image.Rect(1, 1, 11, 11), image.Rect(13, 1, 23, 11), image.Rect(25, 1, 35, 11),
+		image.Rect(1, 13, 11, 23), image.Rect(13, 13, 23, 23), image.Rect(25, 13, 35, 23),
+	}, rects)
+
+	rects = frostutil.SliceGrid(image.Rect(5, 5, 21, 13), frostutil.GridSliceOptions{CellWidth: 8, CellHeight: 8})
+	ass.Equal([]image.Rectangle{image.Rect(5, 5, 13, 13), image.Rect(13, 5, 21, 13)}, rects)
+
+	ass.Nil(frostutil.SliceGrid(image.Rect(0, 0, 10, 10), frostutil.GridSliceOptions{}))
+}
+
+// makeBlobSheet makes a sheet with a few separate shapes on it:
+// a 3x3 square at (2, 2), a diagonal line from (10, 1) to (12, 3), a single faint pixel at (20, 20), a 2x2 square at (6, 10),
+// and a 1x2 piece at (9, 10), which is two pixels away from the 2x2 square.
+func makeBlobSheet() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 24, 24))
+	solid := color.NRGBA{R: 255, A: 255}
+	for y := 2; y < 5; y++ {
+		for x := 2; x < 5; x++ {
+			img.SetNRGBA(x, y, solid)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		img.SetNRGBA(10+i, 1+i, solid)
+	}
+	img.SetNRGBA(20, 20, color.NRGBA{G: 255, A: 10})
+	for y := 10; y < 12; y++ {
+		img.SetNRGBA(6, y, solid)
+		img.SetNRGBA(7, y, solid)
+		img.SetNRGBA(9, y, solid)
+	}
+	return img
+}
+
+func Test_SliceConnected(t *testing.T) {
+	ass := assert.New(t)
+	img := makeBlobSheet()
+
+	rects := frostutil.SliceConnected(img, frostutil.ComponentSliceOptions{DiagonalConnect: true})
+	ass.Equal([]image.Rectangle{
+		image.Rect(10, 1, 13, 4), image.Rect(2, 2, 5, 5), image.Rect(6, 10, 8, 12), image.Rect(9, 10, 10, 12), image.Rect(20, 20, 21, 21),
+	}, rects)
+
+	// Without diagonal connections, the diagonal line falls apart into three pixels, and the faint pixel is under the threshold.
+	rects = frostutil.SliceConnected(img, frostutil.ComponentSliceOptions{AlphaThreshold: 10})
+	ass.Equal([]image.Rectangle{
+		image.Rect(10, 1, 11, 2), image.Rect(2, 2, 5, 5), image.Rect(11, 2, 12, 3), image.Rect(12, 3, 13, 4), image.Rect(6, 10, 8, 12), image.Rect(9, 10, 10, 12),
+	}, rects)
+
+	// Merging pieces that are close together, and dropping specks.
+	rects = frostutil.SliceConnected(img, frostutil.ComponentSliceOptions{DiagonalConnect: true, MergeDistance: 2, MinPixels: 2})
+	ass.Equal([]image.Rectangle{image.Rect(10, 1, 13, 4), image.Rect(2, 2, 5, 5), image.Rect(6, 10, 10, 12)}, rects)
+
+	// The gap between the two pieces is one pixel wide, so they're merged with a MergeDistance of 1.
+	rects = frostutil.SliceConnected(img, frostutil.ComponentSliceOptions{DiagonalConnect: true, MergeDistance: 1, MinPixels: 2})
+	ass.Equal([]image.Rectangle{image.Rect(10, 1, 13, 4), image.Rect(2, 2, 5, 5), image.Rect(6, 10, 10, 12)}, rects)
+
+	// Boxes exactly MergeDistance pixels apart are merged, and boxes one pixel farther apart aren't.
+	gaps := image.NewAlpha(image.Rect(0, 0, 20, 3))
+	gaps.SetAlpha(0, 1, color.Alpha{0xff})
+	gaps.SetAlpha(4, 1, color.Alpha{0xff}) // 3 pixels after the first
+	gaps.SetAlpha(9, 1, color.Alpha{0xff}) // 4 pixels after the second
+	rects = frostutil.SliceConnected(gaps, frostutil.ComponentSliceOptions{MergeDistance: 3})
+	ass.Equal([]image.Rectangle{image.Rect(0, 1, 5, 2), image.Rect(9, 1, 10, 2)}, rects)
+	rects = frostutil.SliceConnected(gaps, frostutil.ComponentSliceOptions{MergeDistance: 2})
+	ass.Len(rects, 3)
+	rects = frostutil.SliceConnected(gaps, frostutil.ComponentSliceOptions{MergeDistance: 4})
+	ass.Equal([]image.Rectangle{image.Rect(0, 1, 10, 2)}, rects)
+
+	// The rectangles are in the image's coordinate space.
+	sub := img.SubImage(image.Rect(1, 1, 8, 8))
+	rects = frostutil.SliceConnected(sub, frostutil.ComponentSliceOptions{})
+	ass.Equal([]image.Rectangle{image.Rect(2, 2, 5, 5)}, rects)
+
+	// Images we have to read with At work too.
+	rects = frostutil.SliceConnected(struct{ image.Image }{img}, frostutil.ComponentSliceOptions{AlphaThreshold: 10})
+	ass.Len(rects, 6)
+}
+
+func Test_ESubImages(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_ESubImages)
+}
+
+func test_ESubImages(t *testing.T) {
+	ass := assert.New(t)
+	eImg := frostutil.NewEImageFromImage(makeBlobSheet(), false)
+	rects := frostutil.SliceConnected(eImg, frostutil.ComponentSliceOptions{DiagonalConnect: true, MinPixels: 2})
+	require.Len(t, rects, 4)
+	subImages := frostutil.ESubImages(eImg, rects)
+	require.Len(t, subImages, 4)
+	for i, sub := range subImages {
+		ass.Equal(rects[i], sub.Bounds())
+	}
+	ass.Equal(color.RGBA{R: 255, A: 255}, subImages[1].At(2, 2))
+}
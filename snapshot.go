@@ -0,0 +1,54 @@
+package frostutil
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// EImageSnapshot is a copy of an *ebiten.Image's pixels, read with a single ReadPixels call, which can be used anywhere an image.Image or
+// draw.Image can. Calling At on an *ebiten.Image reads from the GPU for every pixel, which is very slow when looking at a whole image,
+// while an EImageSnapshot's At reads straight from its pixel buffer.
+//
+// It embeds an *image.RGBA with the same bounds as the *ebiten.Image (so a sub-image's snapshot uses the same coordinates as the sub-image),
+// holding its alpha-premultiplied pixels, so the *image.RGBA methods (At, RGBAAt, Set, SetRGBA, PixOffset, and so on) all work on the snapshot.
+// Changes to the snapshot aren't written back to the *ebiten.Image until Flush is called, and changes to the *ebiten.Image (by drawing on it)
+// aren't seen by the snapshot until Refresh is called.
+//
+// NewNRGBAFromImage, SlowImageCopy, MatchesImage, and the other functions which read *image.RGBAs straight from their pixel buffers
+// do the same with EImageSnapshots, so a snapshot can be passed around without being copied again.
+type EImageSnapshot struct {
+	*image.RGBA
+	eImg *ebiten.Image
+}
+
+// NewEImageSnapshot reads eImg's pixels into a new EImageSnapshot.
+func NewEImageSnapshot(eImg *ebiten.Image) (snap *EImageSnapshot) {
+	snap = &EImageSnapshot{RGBA: image.NewRGBA(eImg.Bounds()), eImg: eImg}
+	eImg.ReadPixels(snap.Pix)
+	return
+}
+
+// EImage returns the *ebiten.Image the snapshot was read from.
+func (snap *EImageSnapshot) EImage() *ebiten.Image {
+	return snap.eImg
+}
+
+// Refresh reads the *ebiten.Image's pixels again, throwing away any changes which haven't been written back with Flush.
+func (snap *EImageSnapshot) Refresh() {
+	snap.eImg.ReadPixels(snap.Pix)
+}
+
+// Flush writes the snapshot's pixels back to the *ebiten.Image with a single WritePixels call.
+func (snap *EImageSnapshot) Flush() {
+	snap.eImg.WritePixels(snap.Pix)
+}
+
+// unwrapSnapshot returns the *image.RGBA inside img if it's an EImageSnapshot, so that the functions which have fast paths for *image.RGBAs
+// can use them, and img itself if it isn't.
+func unwrapSnapshot(img image.Image) image.Image {
+	if snap, ok := img.(*EImageSnapshot); ok {
+		return snap.RGBA
+	}
+	return img
+}
@@ -0,0 +1,81 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EImageSnapshot(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_EImageSnapshot)
+}
+
+func test_EImageSnapshot(t *testing.T) {
+	ass := assert.New(t)
+	img := GetTestImageNRGBA(Alpha_DiagonalGradient)
+	eImg := frostutil.NewEImageFromImage(img, false)
+	snap := frostutil.NewEImageSnapshot(eImg)
+	require.NotNil(t, snap)
+	ass.Same(eImg, snap.EImage())
+	ass.Equal(eImg.Bounds(), snap.Bounds())
+	ass.Equal(color.RGBAModel, snap.ColorModel())
+	ass.Equal(frostutil.NewImageFromEImage(eImg).Pix, snap.Pix)
+	for _, pt := range []image.Point{{0, 0}, {17, 200}, {255, 255}, {128, 3}} {
+		ass.Equal(eImg.At(pt.X, pt.Y), snap.At(pt.X, pt.Y))
+	}
+
+	// a sub-image's snapshot keeps the sub-image's coordinates
+	rect := image.Rect(40, 60, 100, 90)
+	subSnap := frostutil.NewEImageSnapshot(eImg.SubImage(rect).(*ebiten.Image))
+	ass.Equal(rect, subSnap.Bounds())
+	for _, pt := range []image.Point{{40, 60}, {99, 89}, {70, 75}} {
+		ass.Equal(snap.At(pt.X, pt.Y), subSnap.At(pt.X, pt.Y))
+	}
+
+	// changes are written back with Flush, and only to the sub-image
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	subSnap.Set(50, 70, red)
+	ass.Equal(snap.At(50, 70), eImg.At(50, 70))
+	subSnap.Flush()
+	ass.Equal(red, frostutil.NewImageFromEImage(eImg).RGBAAt(50, 70))
+	ass.Equal(snap.RGBAAt(39, 60), frostutil.NewImageFromEImage(eImg).RGBAAt(39, 60))
+
+	// and changes to the *ebiten.Image are read with Refresh
+	ass.NotEqual(red, snap.RGBAAt(50, 70))
+	snap.Refresh()
+	ass.Equal(red, snap.RGBAAt(50, 70))
+	eImg.Fill(color.RGBA{0, 0, 0xff, 0xff})
+	subSnap.Refresh()
+	ass.Equal(color.RGBA{0, 0, 0xff, 0xff}, subSnap.RGBAAt(99, 89))
+}
+
+func Test_EImageSnapshotConsumers(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_EImageSnapshotConsumers)
+}
+
+func test_EImageSnapshotConsumers(t *testing.T) {
+	ass := assert.New(t)
+	img := GetTestImageNRGBA(Alpha_HorizontalGradient)
+	eImg := frostutil.NewEImageFromImage(img, false)
+	snap := frostutil.NewEImageSnapshot(eImg)
+	ass.Equal(frostutil.NewNRGBAFromImage(eImg), frostutil.NewNRGBAFromImage(snap))
+	ass.Equal(frostutil.ContentHash(eImg), frostutil.ContentHash(snap))
+	ass.Equal(frostutil.NewCollisionMask(eImg, 0x40).Count(), frostutil.NewCollisionMask(snap, 0x40).Count())
+	ass.Equal(frostutil.NewImageFromEImage(eImg), frostutil.CopyImage(snap, false))
+	ass.Equal(snap.Pix, frostutil.NewImageFromEImage(frostutil.NewEImageFromImage(snap, false)).Pix)
+
+	// SlowImageCopy reads *ebiten.Images into a snapshot, and writes into a snapshot like an *image.RGBA
+	copied := image.NewRGBA(image.Rect(0, 0, testImgWidth, testImgHeight))
+	require.NoError(t, frostutil.SlowImageCopy(copied, eImg))
+	ass.Equal(snap.Pix, copied.Pix)
+	other := frostutil.NewEImageSnapshot(ebiten.NewImage(testImgWidth, testImgHeight))
+	require.NoError(t, frostutil.SlowImageCopy(other, snap))
+	ass.Equal(snap.Pix, other.Pix)
+	other.Flush()
+	ass.Equal(snap.Pix, frostutil.NewImageFromEImage(other.EImage()).Pix)
+}
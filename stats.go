@@ -0,0 +1,160 @@
+package frostutil
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ImageStats holds statistics about an image's pixels, for linting assets. ComputeImageStats fills it in.
+// All colors are straight (non-premultiplied) 8-bit colors, as from ToNRGBA.
+type ImageStats struct {
+	Width, Height int
+	// Histogram counts the pixels with each value of each component, indexed by component (0 = red, 1 = green, 2 = blue, 3 = alpha) and then value.
+	// Like everything else here, it uses straight colors, so a fully transparent pixel counts as its hidden color.
+	Histogram [4][256]int
+	// Average is the average color. The color components are weighted by alpha, so transparent pixels don't pull the average towards their hidden colors,
+	// and the alpha component is the plain average alpha. If every pixel is fully transparent, the color components are 0.
+	Average color.NRGBA
+	// Median is the per-component median color. Like Average, the color components are weighted by alpha, and the alpha component isn't.
+	Median color.NRGBA
+	// Opaque, Translucent, and Transparent count the pixels with alpha 255, alpha 1 to 254, and alpha 0.
+	Opaque, Translucent, Transparent int
+	// OpaqueBounds is the bounding box of the pixels with alpha 255, and VisibleBounds is the bounding box of the pixels with alpha > 0, both in the image's coordinates.
+	// They're empty if there are no such pixels.
+	OpaqueBounds, VisibleBounds image.Rectangle
+	// UniqueColors is how many different colors there are. All fully transparent pixels count as the same color, transparent black.
+	UniqueColors int
+
+	colorCounts map[color.NRGBA]int
+	weighted    [3][256]uint64 // the color component histograms, weighted by alpha, for the median
+}
+
+// ComputeImageStats works out the ImageStats for img in one pass over its pixels. *image.RGBA and *image.NRGBA images are read straight from their pixel buffers
+// (with the RGBA pixels unmultiplied by UnmultiplyAlphaBytes), *ebiten.Images are read with a single ReadPixels call, and anything else is read with At and converted with ToNRGBA.
+func ComputeImageStats(img image.Image) *ImageStats {
+	img = unwrapSnapshot(img)
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	s := &ImageStats{Width: width, Height: height, colorCounts: map[color.NRGBA]int{}}
+	var totals [4]uint64
+	add := func(x, y int, r, g, b, a byte) {
+		s.Histogram[0][r]++
+		s.Histogram[1][g]++
+		s.Histogram[2][b]++
+		s.Histogram[3][a]++
+		s.weighted[0][r] += uint64(a)
+		s.weighted[1][g] += uint64(a)
+		s.weighted[2][b] += uint64(a)
+		totals[0] += uint64(r) * uint64(a)
+		totals[1] += uint64(g) * uint64(a)
+		totals[2] += uint64(b) * uint64(a)
+		totals[3] += uint64(a)
+		pt := image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x+1, y+1)}
+		switch a {
+		case 0:
+			s.Transparent++
+			s.colorCounts[color.NRGBA{}]++
+			return
+		case 0xff:
+			s.Opaque++
+			s.OpaqueBounds = s.OpaqueBounds.Union(pt)
+		default:
+			s.Translucent++
+		}
+		s.VisibleBounds = s.VisibleBounds.Union(pt)
+		s.colorCounts[color.NRGBA{r, g, b, a}]++
+	}
+
+	var pix []byte
+	stride := width << 2
+	premultiplied := true
+	switch xImg := img.(type) {
+	case *ebiten.Image:
+		pix = make([]byte, 4*width*height)
+		xImg.ReadPixels(pix)
+	case *image.RGBA:
+		pix = xImg.Pix[xImg.PixOffset(bounds.Min.X, bounds.Min.Y):]
+		stride = xImg.Stride
+	case *image.NRGBA:
+		pix = xImg.Pix[xImg.PixOffset(bounds.Min.X, bounds.Min.Y):]
+		stride = xImg.Stride
+		premultiplied = false
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				r, g, b, a := ToNRGBA(img.At(x, y))
+				add(x, y, r, g, b, a)
+			}
+		}
+	}
+	for y := 0; pix != nil && y < height; y++ {
+		idx := y * stride
+		for x := 0; x < width; x++ {
+			r, g, b, a := pix[idx], pix[idx+1], pix[idx+2], pix[idx+3]
+			if premultiplied {
+				r, g, b, a = UnmultiplyAlphaBytes(r, g, b, a)
+			}
+			add(bounds.Min.X+x, bounds.Min.Y+y, r, g, b, a)
+			idx += 4
+		}
+	}
+
+	s.UniqueColors = len(s.colorCounts)
+	if count := uint64(width * height); count > 0 {
+		s.Average.A = byte((totals[3] + count/2) / count)
+		s.Median.A = histogramMedian(s.Histogram[3][:], count)
+	}
+	if totals[3] > 0 {
+		s.Average.R = byte((totals[0] + totals[3]/2) / totals[3])
+		s.Average.G = byte((totals[1] + totals[3]/2) / totals[3])
+		s.Average.B = byte((totals[2] + totals[3]/2) / totals[3])
+		s.Median.R = histogramMedian(s.weighted[0][:], totals[3])
+		s.Median.G = histogramMedian(s.weighted[1][:], totals[3])
+		s.Median.B = histogramMedian(s.weighted[2][:], totals[3])
+	}
+	return s
+}
+
+// histogramMedian returns the lowest value whose cumulative weight in the histogram reaches half of total (the sum of the weights).
+func histogramMedian[T int | uint64](histogram []T, total uint64) byte {
+	var sum uint64
+	for value, weight := range histogram {
+		sum += uint64(weight)
+		if sum*2 >= total {
+			return byte(value)
+		}
+	}
+	return 0xff
+}
+
+// Palette returns the image's unique colors (with all fully transparent pixels counted as transparent black), from the most common to the least common.
+// Colors which are equally common are ordered by their components, from lowest to highest. If limit is > 0, at most limit colors are returned.
+func (s *ImageStats) Palette(limit int) []color.NRGBA {
+	palette := make([]color.NRGBA, 0, len(s.colorCounts))
+	for c := range s.colorCounts {
+		palette = append(palette, c)
+	}
+	sort.Slice(palette, func(i, j int) bool {
+		ci, cj := s.colorCounts[palette[i]], s.colorCounts[palette[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		a, b := palette[i], palette[j]
+		return uint32(a.R)<<24|uint32(a.G)<<16|uint32(a.B)<<8|uint32(a.A) < uint32(b.R)<<24|uint32(b.G)<<16|uint32(b.B)<<8|uint32(b.A)
+	})
+	if limit > 0 && len(palette) > limit {
+		palette = palette[:limit]
+	}
+	return palette
+}
+
+// ColorCount returns how many pixels have the color c. All fully transparent pixels count as transparent black.
+func (s *ImageStats) ColorCount(c color.NRGBA) int {
+	if c.A == 0 {
+		c = color.NRGBA{}
+	}
+	return s.colorCounts[c]
+}
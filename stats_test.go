@@ -0,0 +1,83 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func checkImageStats(ass *assert.Assertions, stats *frostutil.ImageStats, name string) {
+	ass.Equal(4, stats.Width, name)
+	ass.Equal(4, stats.Height, name)
+	ass.Equal(4, stats.Opaque, name)
+	ass.Equal(1, stats.Translucent, name)
+	ass.Equal(11, stats.Transparent, name)
+	ass.Equal(image.Rect(11, 21, 13, 23), stats.OpaqueBounds, name)
+	ass.Equal(image.Rect(11, 21, 14, 24), stats.VisibleBounds, name)
+	ass.Equal(3, stats.UniqueColors, name)
+	ass.Equal(4, stats.Histogram[0][255], name)
+	ass.Equal(11, stats.Histogram[3][0], name)
+	// the transparent pixels don't count towards the average color, but do count towards the average alpha
+	ass.Equal(color.NRGBA{R: 227, B: 28, A: 72}, stats.Average, name)
+	ass.Equal(color.NRGBA{R: 255, A: 0}, stats.Median, name)
+	ass.Equal([]color.NRGBA{{}, {R: 255, A: 255}, {B: 255, A: 128}}, stats.Palette(0), name)
+	ass.Equal([]color.NRGBA{{}, {R: 255, A: 255}}, stats.Palette(2), name)
+	ass.Equal(11, stats.ColorCount(color.NRGBA{G: 255}), name)
+	ass.Equal(4, stats.ColorCount(color.NRGBA{R: 255, A: 255}), name)
+}
+
+func Test_ComputeImageStats(t *testing.T) {
+	ass := assert.New(t)
+	// a 2x2 opaque red square at (11, 21), a half transparent blue pixel at (13, 23), and transparent pixels everywhere else,
+	// one of which has a hidden green color
+	img := image.NewNRGBA(image.Rect(10, 20, 14, 24))
+	for y := 21; y < 23; y++ {
+		for x := 11; x < 13; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	img.SetNRGBA(13, 23, color.NRGBA{B: 255, A: 128})
+	img.SetNRGBA(10, 20, color.NRGBA{G: 255})
+	stats := frostutil.ComputeImageStats(img)
+	checkImageStats(ass, stats, "NRGBA")
+	// only the NRGBA image has the hidden green
+	ass.Equal(1, stats.Histogram[1][255])
+
+	rgba := image.NewRGBA(img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			rgba.Set(x, y, img.At(x, y))
+		}
+	}
+	checkImageStats(ass, frostutil.ComputeImageStats(rgba), "RGBA")
+	checkImageStats(ass, frostutil.ComputeImageStats(struct{ image.Image }{img}), "other")
+
+	empty := frostutil.ComputeImageStats(image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+	ass.Equal(color.NRGBA{}, empty.Average)
+	ass.Equal(color.NRGBA{}, empty.Median)
+	ass.True(empty.VisibleBounds.Empty())
+	ass.Equal(1, empty.UniqueColors)
+}
+
+func Test_ComputeImageStatsEImage(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_ComputeImageStatsEImage)
+}
+
+func test_ComputeImageStatsEImage(t *testing.T) {
+	ass := assert.New(t)
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 1; y < 3; y++ {
+		for x := 1; x < 3; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	img.SetNRGBA(3, 3, color.NRGBA{B: 255, A: 128})
+	// the *ebiten.Image is read with ReadPixels
+	stats := frostutil.ComputeImageStats(frostutil.NewEImageFromImage(img, false))
+	ass.Equal(4, stats.Opaque)
+	ass.Equal(image.Rect(1, 1, 3, 3), stats.OpaqueBounds)
+	ass.Equal(3, stats.UniqueColors)
+}
@@ -0,0 +1,493 @@
+package frostutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// This file implements reading and writing Truevision TGA (Targa) images, uncompressed or RLE compressed, in truecolor, grayscale, and color-mapped flavors.
+// Go's standard library doesn't support TGA, and there's no magic number at the start of the file, so we don't register it with image.RegisterFormat.
+// DecodeImage and LoadImage recognize it by checking whether the header makes sense, though.
+
+// TGA image types, from byte 2 of the header.
+const (
+	tgaTypeColorMapped    = 1
+	tgaTypeTrueColor      = 2
+	tgaTypeGray           = 3
+	tgaTypeRLEColorMapped = 9
+	tgaTypeRLETrueColor   = 10
+	tgaTypeRLEGray        = 11
+	tgaTypeRLEFlag        = 8 // set in the RLE image types
+)
+
+// TGA alpha attribute types, from the last byte of the extension area.
+const (
+	tgaAttrNoAlpha       = 0 // no alpha data
+	tgaAttrUndefIgnore   = 1 // undefined data in the alpha field, which can be ignored
+	tgaAttrUndefRetain   = 2 // undefined data in the alpha field, which should be retained (but still isn't alpha)
+	tgaAttrAlpha         = 3 // useful alpha
+	tgaAttrPremultiplied = 4 // useful alpha, and the color components are premultiplied by it
+)
+
+const (
+	tgaHeaderLen        = 18
+	tgaFooterLen        = 26
+	tgaExtensionLen     = 495
+	tgaSignature        = "TRUEVISION-XFILE.\x00"
+	tgaDescRightToLeft  = 0x10 // image descriptor bit 4
+	tgaDescTopToBottom  = 0x20 // image descriptor bit 5
+	tgaDescAlphaBits    = 0x0f // image descriptor bits 0-3, the number of attribute (alpha) bits per pixel
+	tgaDescInterleaving = 0xc0 // image descriptor bits 6-7, which must be zero
+	tgaMaxRLEPacket     = 128
+	tgaMaxPixels        = 1 << 28 // stops a corrupt header from making us allocate absurd amounts of memory
+)
+
+// TGAOptions are the options for EncodeTGA.
+type TGAOptions struct {
+	RLE bool // If true, the image data is run-length encoded.
+}
+
+// tgaHeader holds the fields of the 18 byte TGA header.
+type tgaHeader struct {
+	idLength          int
+	colorMapType      byte
+	imageType         byte
+	colorMapFirst     int
+	colorMapLength    int
+	colorMapEntryBits int
+	width, height     int
+	pixelBits         int
+	descriptor        byte
+}
+
+// parseTGAHeader parses and validates the TGA header at the start of data.
+func parseTGAHeader(data []byte) (h tgaHeader, err error) {
+	if len(data) < tgaHeaderLen {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	h = tgaHeader{
+		idLength:          int(data[0]),
+		colorMapType:      data[1],
+		imageType:         data[2],
+		colorMapFirst:     int(binary.LittleEndian.Uint16(data[3:5])),
+		colorMapLength:    int(binary.LittleEndian.Uint16(data[5:7])),
+		colorMapEntryBits: int(data[7]),
+		width:             int(binary.LittleEndian.Uint16(data[12:14])),
+		height:            int(binary.LittleEndian.Uint16(data[14:16])),
+		pixelBits:         int(data[16]),
+		descriptor:        data[17],
+	}
+	colorMapped := false
+	switch h.imageType {
+	case tgaTypeColorMapped, tgaTypeRLEColorMapped:
+		colorMapped = true
+		if h.colorMapType != 1 {
+			err = errors.New("tga: color-mapped image without a color map")
+		} else if h.pixelBits != 8 && h.pixelBits != 16 {
+			err = fmt.Errorf("tga: unsupported color map index size %v", h.pixelBits)
+		}
+	case tgaTypeTrueColor, tgaTypeRLETrueColor:
+		if h.pixelBits != 15 && h.pixelBits != 16 && h.pixelBits != 24 && h.pixelBits != 32 {
+			err = fmt.Errorf("tga: unsupported truecolor pixel size %v", h.pixelBits)
+		}
+	case tgaTypeGray, tgaTypeRLEGray:
+		if h.pixelBits != 8 && h.pixelBits != 16 {
+			err = fmt.Errorf("tga: unsupported grayscale pixel size %v", h.pixelBits)
+		}
+	default:
+		err = fmt.Errorf("tga: unsupported image type %v", h.imageType)
+	}
+	if err != nil {
+		return
+	}
+	if h.colorMapType > 1 {
+		err = fmt.Errorf("tga: invalid color map type %v", h.colorMapType)
+	} else if h.colorMapType == 1 && (h.colorMapEntryBits != 15 && h.colorMapEntryBits != 16 && h.colorMapEntryBits != 24 && h.colorMapEntryBits != 32) {
+		err = fmt.Errorf("tga: unsupported color map entry size %v", h.colorMapEntryBits)
+	} else if colorMapped && h.colorMapLength == 0 {
+		err = errors.New("tga: empty color map")
+	} else if h.width == 0 || h.height == 0 || h.width*h.height > tgaMaxPixels {
+		err = fmt.Errorf("tga: invalid image dimensions %vx%v", h.width, h.height)
+	} else if h.descriptor&tgaDescInterleaving != 0 {
+		err = errors.New("tga: interleaved images are not supported")
+	}
+	return
+}
+
+// isTGAHeader reports whether header looks like the start of a TGA file we can decode. TGA has no magic number, so this is only a guess.
+func isTGAHeader(header []byte) bool {
+	_, err := parseTGAHeader(header)
+	return err == nil
+}
+
+// DecodeTGAConfig returns the color model and dimensions of a TGA image without decoding the entire image.
+func DecodeTGAConfig(r io.Reader) (cfg image.Config, err error) {
+	var data [tgaHeaderLen]byte
+	if _, err = io.ReadFull(r, data[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	h, err := parseTGAHeader(data[:])
+	if err == nil {
+		cfg = image.Config{ColorModel: color.NRGBAModel, Width: h.width, Height: h.height}
+	}
+	return
+}
+
+// tgaAttributesType returns the alpha attribute type from the TGA 2.0 extension area, if the file has one. Otherwise it returns -1.
+func tgaAttributesType(data []byte) int {
+	if len(data) < tgaHeaderLen+tgaFooterLen || string(data[len(data)-len(tgaSignature):]) != tgaSignature {
+		return -1
+	}
+	extOffset := int(binary.LittleEndian.Uint32(data[len(data)-tgaFooterLen:]))
+	if extOffset < tgaHeaderLen || extOffset+tgaExtensionLen > len(data)-tgaFooterLen {
+		return -1
+	}
+	if binary.LittleEndian.Uint16(data[extOffset:]) < tgaExtensionLen {
+		return -1
+	}
+	return int(data[extOffset+tgaExtensionLen-1])
+}
+
+// expand5 expands a 5-bit color component to 8 bits.
+func expand5(v uint16) byte {
+	v &= 0x1f
+	return byte(v<<3 | v>>2)
+}
+
+// tgaColor converts one little-endian TGA pixel (or color map entry) of the given size to NRGBA components.
+// If useAlpha is false, the alpha component is always 0xff.
+func tgaColor(b []byte, bits int, gray bool, useAlpha bool) (px [4]byte) {
+	px[3] = 0xff
+	if gray {
+		px[0], px[1], px[2] = b[0], b[0], b[0]
+		if bits == 16 && useAlpha {
+			px[3] = b[1]
+		}
+		return
+	}
+	switch bits {
+	case 15, 16:
+		v := uint16(b[0]) | uint16(b[1])<<8
+		px[0], px[1], px[2] = expand5(v>>10), expand5(v>>5), expand5(v)
+		if bits == 16 && useAlpha && v&0x8000 == 0 {
+			px[3] = 0
+		}
+	case 24:
+		px[0], px[1], px[2] = b[2], b[1], b[0]
+	case 32:
+		px[0], px[1], px[2] = b[2], b[1], b[0]
+		if useAlpha {
+			px[3] = b[3]
+		}
+	}
+	return
+}
+
+// DecodeTGA decodes a TGA image from r and returns it as an *image.NRGBA.
+// The alpha channel is used if the image descriptor says there are alpha bits (or, for color-mapped images, if the color map entries are 32-bit), unless the TGA 2.0 extension area says the alpha data is undefined or absent.
+// If the extension area says the color components are premultiplied, the premultiplication is removed with UnmultiplyAlphaBytes.
+// Since TGA stores non-premultiplied colors (normally), color components are preserved where alpha is zero.
+func DecodeTGA(r io.Reader) (img image.Image, err error) {
+	// We need the footer at the end of the file to know how to treat alpha, so we read the whole thing.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	h, err := parseTGAHeader(data)
+	if err != nil {
+		return
+	}
+	pos := tgaHeaderLen + h.idLength
+
+	gray := h.imageType&^tgaTypeRLEFlag == tgaTypeGray
+	colorMapped := h.imageType&^tgaTypeRLEFlag == tgaTypeColorMapped
+	// Color-mapped pixels are indices with no attribute bits of their own, so their alpha comes from 32-bit color map entries.
+	useAlpha := h.descriptor&tgaDescAlphaBits != 0 || (colorMapped && h.colorMapEntryBits == 32)
+	premultiplied := false
+	switch tgaAttributesType(data) {
+	case tgaAttrNoAlpha, tgaAttrUndefIgnore, tgaAttrUndefRetain:
+		useAlpha = false
+	case tgaAttrAlpha:
+		useAlpha = true
+	case tgaAttrPremultiplied:
+		useAlpha = true
+		premultiplied = true
+	}
+
+	var palette [][4]byte
+	if h.colorMapType == 1 {
+		entryBytes := (h.colorMapEntryBits + 7) >> 3
+		end := pos + h.colorMapLength*entryBytes
+		if end > len(data) {
+			err = io.ErrUnexpectedEOF
+			return
+		}
+		if colorMapped {
+			palette = make([][4]byte, h.colorMapLength)
+			for i := range palette {
+				palette[i] = tgaColor(data[pos+i*entryBytes:], h.colorMapEntryBits, false, useAlpha)
+			}
+		}
+		pos = end
+	}
+
+	// Read the pixels into a buffer of raw TGA pixels, expanding RLE packets if there are any.
+	pixelBytes := (h.pixelBits + 7) >> 3
+	rawLen := h.width * h.height * pixelBytes
+	var raw []byte
+	if h.imageType&tgaTypeRLEFlag == 0 {
+		if pos+rawLen > len(data) {
+			err = io.ErrUnexpectedEOF
+			return
+		}
+		raw = data[pos : pos+rawLen]
+	} else {
+		raw = make([]byte, rawLen)
+		// packets are allowed to cross scanlines, so we treat the pixel data as one long run
+		for out := 0; out < rawLen; {
+			if pos >= len(data) {
+				err = io.ErrUnexpectedEOF
+				return
+			}
+			packet := data[pos]
+			pos++
+			count := int(packet&0x7f) + 1
+			if out+count*pixelBytes > rawLen {
+				err = errors.New("tga: RLE packet runs past the end of the image")
+				return
+			}
+			if packet&0x80 != 0 {
+				if pos+pixelBytes > len(data) {
+					err = io.ErrUnexpectedEOF
+					return
+				}
+				for i := 0; i < count; i++ {
+					copy(raw[out:], data[pos:pos+pixelBytes])
+					out += pixelBytes
+				}
+				pos += pixelBytes
+			} else {
+				if pos+count*pixelBytes > len(data) {
+					err = io.ErrUnexpectedEOF
+					return
+				}
+				copy(raw[out:], data[pos:pos+count*pixelBytes])
+				out += count * pixelBytes
+				pos += count * pixelBytes
+			}
+		}
+	}
+
+	nImg := image.NewNRGBA(image.Rect(0, 0, h.width, h.height))
+	rightToLeft := h.descriptor&tgaDescRightToLeft != 0
+	topToBottom := h.descriptor&tgaDescTopToBottom != 0
+	var px [4]byte
+	idx := 0
+	for row := 0; row < h.height; row++ {
+		y := row
+		if !topToBottom {
+			y = h.height - 1 - row
+		}
+		for col := 0; col < h.width; col++ {
+			x := col
+			if rightToLeft {
+				x = h.width - 1 - col
+			}
+			if colorMapped {
+				index := int(raw[idx])
+				if pixelBytes == 2 {
+					index |= int(raw[idx+1]) << 8
+				}
+				index -= h.colorMapFirst
+				if index < 0 || index >= len(palette) {
+					err = fmt.Errorf("tga: color map index %v out of range", index+h.colorMapFirst)
+					return
+				}
+				px = palette[index]
+			} else {
+				px = tgaColor(raw[idx:], h.pixelBits, gray, useAlpha)
+			}
+			if premultiplied {
+				px[0], px[1], px[2], px[3] = UnmultiplyAlphaBytes(px[0], px[1], px[2], px[3])
+			}
+			oIdx := nImg.PixOffset(x, y)
+			nImg.Pix[oIdx] = px[0]
+			nImg.Pix[oIdx+1] = px[1]
+			nImg.Pix[oIdx+2] = px[2]
+			nImg.Pix[oIdx+3] = px[3]
+			idx += pixelBytes
+		}
+	}
+	img = nImg
+	return
+}
+
+// EncodeTGA writes img to w as a TGA image, with a TGA 2.0 extension area and footer which say whether the alpha channel is meaningful.
+// *image.Gray images are written as 8-bit grayscale, and *image.Paletted images with at most 256 colors are written as color-mapped images with 8-bit indices
+// (and 32-bit color map entries if any of the colors aren't opaque, or 24-bit ones if they all are).
+// Everything else is written as 24-bit truecolor if every pixel is opaque, or 32-bit truecolor with 8 alpha bits if not.
+// Truecolor images are converted with NewNRGBAFromImage (unless they're already an *image.NRGBA), so color components are preserved where alpha is zero.
+// The image is written top to bottom. If opts is nil, the image data is not compressed.
+func EncodeTGA(w io.Writer, img image.Image, opts *TGAOptions) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || width > 0xffff || height > 0xffff {
+		return fmt.Errorf("tga: can't encode an image with dimensions %vx%v", width, height)
+	}
+	rle := opts != nil && opts.RLE
+
+	var header [tgaHeaderLen]byte
+	var colorMap []byte
+	var pixels []byte // the raw TGA pixel data, with no padding between rows
+	pixelBytes := 0
+	hasAlpha := false
+	if gImg, ok := img.(*image.Gray); ok {
+		header[2] = tgaTypeGray
+		pixelBytes = 1
+		pixels = make([]byte, width*height)
+		for y := 0; y < height; y++ {
+			idx := gImg.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(pixels[y*width:], gImg.Pix[idx:idx+width])
+		}
+	} else if pImg, ok := img.(*image.Paletted); ok && len(pImg.Palette) > 0 && len(pImg.Palette) <= 256 {
+		header[1] = 1
+		header[2] = tgaTypeColorMapped
+		pixelBytes = 1
+		entries := make([][4]byte, len(pImg.Palette))
+		for i, c := range pImg.Palette {
+			entries[i][0], entries[i][1], entries[i][2], entries[i][3] = ToNRGBA(c)
+			hasAlpha = hasAlpha || entries[i][3] != 0xff
+		}
+		entryBytes := 3
+		if hasAlpha {
+			entryBytes = 4
+		}
+		colorMap = make([]byte, 0, len(entries)*entryBytes)
+		for _, e := range entries {
+			colorMap = append(colorMap, e[2], e[1], e[0])
+			if hasAlpha {
+				colorMap = append(colorMap, e[3])
+			}
+		}
+		binary.LittleEndian.PutUint16(header[5:7], uint16(len(entries)))
+		header[7] = byte(entryBytes * 8)
+		pixels = make([]byte, width*height)
+		for y := 0; y < height; y++ {
+			idx := pImg.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(pixels[y*width:], pImg.Pix[idx:idx+width])
+		}
+	} else {
+		nImg, ok := img.(*image.NRGBA)
+		if !ok {
+			nImg = NewNRGBAFromImage(img)
+		}
+		nBounds := nImg.Bounds()
+		for y := 0; y < height && !hasAlpha; y++ {
+			idx := nImg.PixOffset(nBounds.Min.X, nBounds.Min.Y+y)
+			for x := 0; x < width; x++ {
+				if nImg.Pix[idx+3] != 0xff {
+					hasAlpha = true
+					break
+				}
+				idx += 4
+			}
+		}
+		header[2] = tgaTypeTrueColor
+		pixelBytes = 3
+		if hasAlpha {
+			pixelBytes = 4
+		}
+		pixels = make([]byte, 0, width*height*pixelBytes)
+		for y := 0; y < height; y++ {
+			idx := nImg.PixOffset(nBounds.Min.X, nBounds.Min.Y+y)
+			for x := 0; x < width; x++ {
+				pixels = append(pixels, nImg.Pix[idx+2], nImg.Pix[idx+1], nImg.Pix[idx])
+				if hasAlpha {
+					pixels = append(pixels, nImg.Pix[idx+3])
+				}
+				idx += 4
+			}
+		}
+	}
+	if rle {
+		header[2] |= tgaTypeRLEFlag
+	}
+	binary.LittleEndian.PutUint16(header[12:14], uint16(width))
+	binary.LittleEndian.PutUint16(header[14:16], uint16(height))
+	header[16] = byte(pixelBytes * 8)
+	header[17] = tgaDescTopToBottom
+	if hasAlpha && pixelBytes == 4 {
+		// The descriptor's alpha bits count the attribute bits in each pixel, so they're only set for 32-bit pixels. Color-mapped pixels are
+		// just indices, and readers get the alpha from the 32-bit color map entries.
+		header[17] |= 8
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header[:])
+	bw.Write(colorMap)
+	dataLen := tgaHeaderLen + len(colorMap)
+	if rle {
+		var buf bytes.Buffer
+		for y := 0; y < height; y++ {
+			writeTGARLERow(&buf, pixels[y*width*pixelBytes:(y+1)*width*pixelBytes], pixelBytes)
+		}
+		dataLen += buf.Len()
+		bw.Write(buf.Bytes())
+	} else {
+		dataLen += len(pixels)
+		bw.Write(pixels)
+	}
+
+	// TGA 2.0 extension area, with everything but its size and the alpha attributes type left blank, followed by the footer.
+	var ext [tgaExtensionLen]byte
+	binary.LittleEndian.PutUint16(ext[0:2], tgaExtensionLen)
+	if hasAlpha {
+		ext[tgaExtensionLen-1] = tgaAttrAlpha
+	} else {
+		ext[tgaExtensionLen-1] = tgaAttrNoAlpha
+	}
+	bw.Write(ext[:])
+	var footer [tgaFooterLen]byte
+	binary.LittleEndian.PutUint32(footer[0:4], uint32(dataLen))
+	copy(footer[8:], tgaSignature)
+	bw.Write(footer[:])
+	return bw.Flush()
+}
+
+// writeTGARLERow run-length encodes one row of raw TGA pixels into buf. Packets never cross rows, as the TGA spec recommends.
+func writeTGARLERow(buf *bytes.Buffer, row []byte, pixelBytes int) {
+	n := len(row) / pixelBytes
+	same := func(a, b int) bool {
+		return bytes.Equal(row[a*pixelBytes:(a+1)*pixelBytes], row[b*pixelBytes:(b+1)*pixelBytes])
+	}
+	for i := 0; i < n; {
+		run := 1
+		for i+run < n && run < tgaMaxRLEPacket && same(i, i+run) {
+			run++
+		}
+		if run > 1 {
+			buf.WriteByte(0x80 | byte(run-1))
+			buf.Write(row[i*pixelBytes : (i+1)*pixelBytes])
+			i += run
+			continue
+		}
+		// Collect raw pixels until we find two in a row that match (which would be better off in a run packet) or fill the packet.
+		count := 1
+		for i+count < n && count < tgaMaxRLEPacket && !(i+count+1 < n && same(i+count, i+count+1)) {
+			count++
+		}
+		buf.WriteByte(byte(count - 1))
+		buf.Write(row[i*pixelBytes : (i+count)*pixelBytes])
+		i += count
+	}
+}
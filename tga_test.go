@@ -0,0 +1,284 @@
+package frostutil_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTGA builds a TGA file from its parts. If attrType is >= 0, it adds a TGA 2.0 extension area and footer with that alpha attributes type.
+func makeTGA(header []byte, colorMap []byte, data []byte, attrType int) []byte {
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.Write(colorMap)
+	buf.Write(data)
+	if attrType >= 0 {
+		extOffset := buf.Len()
+		ext := make([]byte, 495)
+		binary.LittleEndian.PutUint16(ext, 495)
+		ext[494] = byte(attrType)
+		buf.Write(ext)
+		footer := make([]byte, 8)
+		binary.LittleEndian.PutUint32(footer, uint32(extOffset))
+		buf.Write(footer)
+		buf.WriteString("TRUEVISION-XFILE.\x00")
+	}
+	return buf.Bytes()
+}
+
+// tgaHeader builds an 18 byte TGA header.
+func tgaHeader(colorMapType, imageType byte, cmapFirst, cmapLen uint16, cmapBits byte, width, height uint16, pixelBits, descriptor byte) []byte {
+	h := make([]byte, 18)
+	h[1] = colorMapType
+	h[2] = imageType
+	binary.LittleEndian.PutUint16(h[3:], cmapFirst)
+	binary.LittleEndian.PutUint16(h[5:], cmapLen)
+	h[7] = cmapBits
+	binary.LittleEndian.PutUint16(h[12:], width)
+	binary.LittleEndian.PutUint16(h[14:], height)
+	h[16] = pixelBits
+	h[17] = descriptor
+	return h
+}
+
+func decodeTGANRGBA(t *testing.T, data []byte) *image.NRGBA {
+	img, err := frostutil.DecodeTGA(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img.(*image.NRGBA)
+}
+
+func Test_TGARoundTrip(t *testing.T) {
+	ass := assert.New(t)
+	for _, rle := range []bool{false, true} {
+		opts := &frostutil.TGAOptions{RLE: rle}
+		for alphaTestMode := AlphaTestMode(0); alphaTestMode < NumAlphaTestModes; alphaTestMode++ {
+			var buf bytes.Buffer
+			require.NoError(t, frostutil.EncodeTGA(&buf, GetTestImageNRGBA(alphaTestMode), opts))
+			img, err := frostutil.DecodeTGA(&buf)
+			require.NoError(t, err)
+			ass.NoError(CheckImagePattern(img, alphaTestMode), "rle=%v", rle)
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, frostutil.EncodeTGA(&buf, GetTestImageRGBA(Alpha_FF), opts))
+		ass.Equal(byte(24), buf.Bytes()[16])
+		img, err := frostutil.DecodeTGA(&buf)
+		require.NoError(t, err)
+		ass.NoError(CheckImagePattern(img, Alpha_FF))
+
+		gray := image.NewGray(image.Rect(3, 4, 9, 8))
+		for i := range gray.Pix {
+			gray.Pix[i] = byte(i / 3 * 17)
+		}
+		buf.Reset()
+		require.NoError(t, frostutil.EncodeTGA(&buf, gray, opts))
+		ass.Equal(byte(3), buf.Bytes()[2]&^8)
+		nImg := decodeTGANRGBA(t, buf.Bytes())
+		ass.Equal(image.Rect(0, 0, 6, 4), nImg.Bounds())
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 6; x++ {
+				g := gray.GrayAt(x+3, y+4).Y
+				ass.Equal(color.NRGBA{g, g, g, 0xff}, nImg.NRGBAAt(x, y))
+			}
+		}
+
+		palette := color.Palette{color.NRGBA{10, 20, 30, 0}, color.NRGBA{40, 50, 60, 128}, color.NRGBA{70, 80, 90, 255}}
+		pal := image.NewPaletted(image.Rect(0, 0, 5, 2), palette)
+		for i := range pal.Pix {
+			pal.Pix[i] = byte(i % 3)
+		}
+		buf.Reset()
+		require.NoError(t, frostutil.EncodeTGA(&buf, pal, opts))
+		ass.Equal(byte(1), buf.Bytes()[2]&^8)
+		nImg = decodeTGANRGBA(t, buf.Bytes())
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 5; x++ {
+				ass.Equal(palette[pal.ColorIndexAt(x, y)], nImg.NRGBAAt(x, y))
+			}
+		}
+	}
+}
+
+// readTGAByTheSpec is a separate, minimal reader for the uncompressed top-to-bottom TGA files EncodeTGA writes, which reads them the way the
+// TGA spec (and readers like stb_image) do, without looking at the extension area: truecolor pixels have alpha if the descriptor says they
+// have attribute bits, and color-mapped pixels are indices into the color map, whose entries have alpha if they're 32-bit.
+func readTGAByTheSpec(t *testing.T, data []byte) *image.NRGBA {
+	require.GreaterOrEqual(t, len(data), 18)
+	idLength, colorMapType, imageType := int(data[0]), data[1], data[2]
+	cmapLen, cmapBits := int(binary.LittleEndian.Uint16(data[5:])), int(data[7])
+	width, height := int(binary.LittleEndian.Uint16(data[12:])), int(binary.LittleEndian.Uint16(data[14:]))
+	pixelBits, descriptor := int(data[16]), data[17]
+	require.Equal(t, byte(0x20), descriptor&0xf0, "top to bottom, left to right")
+	pos := 18 + idLength
+	var cmap []color.NRGBA
+	if colorMapType == 1 {
+		entryBytes := cmapBits / 8
+		for i := 0; i < cmapLen; i++ {
+			e := data[pos+i*entryBytes:]
+			c := color.NRGBA{e[2], e[1], e[0], 0xff}
+			if cmapBits == 32 {
+				c.A = e[3]
+			}
+			cmap = append(cmap, c)
+		}
+		pos += cmapLen * entryBytes
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	pixelBytes := pixelBits / 8
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := data[pos:]
+			switch imageType {
+			case 1:
+				require.Equal(t, 8, pixelBits)
+				img.SetNRGBA(x, y, cmap[p[0]])
+			case 2:
+				c := color.NRGBA{p[2], p[1], p[0], 0xff}
+				if pixelBits == 32 && descriptor&0x0f == 8 {
+					c.A = p[3]
+				}
+				img.SetNRGBA(x, y, c)
+			default:
+				require.Fail(t, "unexpected image type", "%v", imageType)
+			}
+			pos += pixelBytes
+		}
+	}
+	return img
+}
+
+// Checks EncodeTGA's output against readTGAByTheSpec rather than DecodeTGA, so that a mistake made the same way in both of them can't hide.
+func Test_EncodeTGAOtherReader(t *testing.T) {
+	ass := assert.New(t)
+	palette := color.Palette{color.NRGBA{10, 20, 30, 0}, color.NRGBA{40, 50, 60, 128}, color.NRGBA{70, 80, 90, 255}}
+	pal := image.NewPaletted(image.Rect(0, 0, 5, 2), palette)
+	for i := range pal.Pix {
+		pal.Pix[i] = byte(i % 3)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeTGA(&buf, pal, nil))
+	ass.Equal(byte(32), buf.Bytes()[7], "32-bit color map entries")
+	ass.Equal(byte(0), buf.Bytes()[17]&0x0f, "color-mapped pixels have no attribute bits")
+	nImg := readTGAByTheSpec(t, buf.Bytes())
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 5; x++ {
+			ass.Equal(palette[pal.ColorIndexAt(x, y)], nImg.NRGBAAt(x, y))
+		}
+	}
+	// an opaque palette gets 24-bit entries
+	opaque := image.NewPaletted(pal.Rect, color.Palette{color.NRGBA{1, 2, 3, 255}, color.NRGBA{4, 5, 6, 255}})
+	opaque.Pix[3] = 1
+	buf.Reset()
+	require.NoError(t, frostutil.EncodeTGA(&buf, opaque, nil))
+	ass.Equal(byte(24), buf.Bytes()[7])
+	ass.Equal(color.NRGBA{4, 5, 6, 255}, readTGAByTheSpec(t, buf.Bytes()).NRGBAAt(3, 0))
+
+	// truecolor images with alpha say they have 8 attribute bits
+	src := GetTestImageNRGBA(Alpha_DiagonalGradient)
+	buf.Reset()
+	require.NoError(t, frostutil.EncodeTGA(&buf, src, nil))
+	ass.Equal(byte(8), buf.Bytes()[17]&0x0f)
+	ass.NoError(CheckImagePattern(readTGAByTheSpec(t, buf.Bytes()), Alpha_DiagonalGradient))
+}
+
+// A bottom-up, RLE compressed, 16-bit image with a one bit alpha, whose packets cross scanlines.
+func Test_DecodeTGA16BitRLEBottomUp(t *testing.T) {
+	// 16-bit pixels are little-endian ARRRRRGG GGGBBBBB
+	data := []byte{
+		0x82, 0x00, 0xfc, // a run packet of 3 red pixels with the alpha bit set
+		0x02,       // a raw packet of 3 pixels
+		0x1f, 0x00, // blue with the alpha bit clear
+		0xe0, 0x83, // green with the alpha bit set
+		0x1f, 0x00, // blue with the alpha bit clear
+	}
+	header := tgaHeader(0, 10, 0, 0, 0, 3, 2, 16, 1)
+	nImg := decodeTGANRGBA(t, makeTGA(header, nil, data, -1))
+	ass := assert.New(t)
+	// The first row in the file is the bottom row.
+	ass.Equal(color.NRGBA{0xff, 0, 0, 0xff}, nImg.NRGBAAt(0, 1))
+	ass.Equal(color.NRGBA{0xff, 0, 0, 0xff}, nImg.NRGBAAt(2, 1))
+	ass.Equal(color.NRGBA{0, 0, 0xff, 0}, nImg.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{0, 0xff, 0, 0xff}, nImg.NRGBAAt(1, 0))
+	ass.Equal(color.NRGBA{0, 0, 0xff, 0}, nImg.NRGBAAt(2, 0))
+
+	// Without any alpha bits in the descriptor, the attribute bit must be ignored.
+	header[17] = 0
+	nImg = decodeTGANRGBA(t, makeTGA(header, nil, data, -1))
+	ass.Equal(color.NRGBA{0, 0, 0xff, 0xff}, nImg.NRGBAAt(0, 0))
+}
+
+func Test_DecodeTGAAlphaAttributes(t *testing.T) {
+	ass := assert.New(t)
+	// one 32-bit pixel, stored as BGRA, right to left and top to bottom
+	data := []byte{30, 20, 10, 0, 60, 50, 40, 100}
+	header := tgaHeader(0, 2, 0, 0, 0, 2, 1, 32, 0x38)
+	nImg := decodeTGANRGBA(t, makeTGA(header, nil, data, -1))
+	ass.Equal(color.NRGBA{10, 20, 30, 0}, nImg.NRGBAAt(1, 0))
+	ass.Equal(color.NRGBA{40, 50, 60, 100}, nImg.NRGBAAt(0, 0))
+
+	// the extension area says the alpha channel is garbage
+	nImg = decodeTGANRGBA(t, makeTGA(header, nil, data, 2))
+	ass.Equal(color.NRGBA{10, 20, 30, 0xff}, nImg.NRGBAAt(1, 0))
+
+	// no alpha bits in the descriptor, but the extension area says the alpha is premultiplied
+	header[17] = 0x30
+	nImg = decodeTGANRGBA(t, makeTGA(header, nil, data, 4))
+	r, g, b, a := frostutil.UnmultiplyAlphaBytes(40, 50, 60, 100)
+	ass.Equal(color.NRGBA{r, g, b, a}, nImg.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{10, 20, 30, 0}, nImg.NRGBAAt(1, 0))
+
+	// no alpha bits and no extension area
+	nImg = decodeTGANRGBA(t, makeTGA(header, nil, data, -1))
+	ass.Equal(color.NRGBA{40, 50, 60, 0xff}, nImg.NRGBAAt(0, 0))
+}
+
+func Test_DecodeTGAColorMapped(t *testing.T) {
+	ass := assert.New(t)
+	// 16-bit indices into a 24-bit color map which starts at index 5, with an image ID
+	colorMap := []byte{0, 0, 255, 0, 255, 0}
+	header := tgaHeader(1, 1, 5, 2, 24, 2, 1, 16, 0x20)
+	header[0] = 3
+	data := []byte{6, 0, 5, 0}
+	nImg := decodeTGANRGBA(t, makeTGA(header, append([]byte("abc"), colorMap...), data, -1))
+	ass.Equal(color.NRGBA{0, 255, 0, 255}, nImg.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{255, 0, 0, 255}, nImg.NRGBAAt(1, 0))
+
+	// an index before the start of the color map
+	data = []byte{4, 0, 5, 0}
+	_, err := frostutil.DecodeTGA(bytes.NewReader(makeTGA(header, append([]byte("abc"), colorMap...), data, -1)))
+	ass.Error(err)
+
+	// 32-bit color map entries have alpha, even though the descriptor says the pixels (which are just indices) have no attribute bits
+	header = tgaHeader(1, 1, 0, 2, 32, 2, 1, 8, 0x20)
+	nImg = decodeTGANRGBA(t, makeTGA(header, []byte{30, 20, 10, 0, 60, 50, 40, 100}, []byte{1, 0}, -1))
+	ass.Equal(color.NRGBA{40, 50, 60, 100}, nImg.NRGBAAt(0, 0))
+	ass.Equal(color.NRGBA{10, 20, 30, 0}, nImg.NRGBAAt(1, 0))
+}
+
+func Test_DecodeTGAGray16(t *testing.T) {
+	header := tgaHeader(0, 3, 0, 0, 0, 1, 1, 16, 0x28)
+	nImg := decodeTGANRGBA(t, makeTGA(header, nil, []byte{77, 33}, -1))
+	assert.Equal(t, color.NRGBA{77, 77, 77, 33}, nImg.NRGBAAt(0, 0))
+}
+
+func Test_TGADetection(t *testing.T) {
+	ass := assert.New(t)
+	var buf bytes.Buffer
+	require.NoError(t, frostutil.EncodeTGA(&buf, GetTestImageNRGBA(Alpha_VerticalGradient), &frostutil.TGAOptions{RLE: true}))
+	img, format, err := frostutil.DecodeImage(&buf)
+	require.NoError(t, err)
+	ass.Equal(frostutil.ImageFormatTGA, format)
+	ass.NoError(CheckImagePattern(img, Alpha_VerticalGradient))
+	ass.Equal(frostutil.ImageFormatTGA, frostutil.ImageFormatFromFilename("legacy.TGA"))
+
+	_, err = frostutil.DecodeTGA(bytes.NewReader(tgaHeader(0, 2, 0, 0, 0, 10, 10, 32, 0x28)))
+	ass.Error(err)
+	_, err = frostutil.DecodeTGA(bytes.NewReader(tgaHeader(0, 7, 0, 0, 0, 10, 10, 32, 0x28)))
+	ass.Error(err)
+}
@@ -0,0 +1,101 @@
+package frostutil
+
+import (
+	"image"
+)
+
+// TrimTransparent trims away the borders of img whose pixels all have alpha components <= threshold, and returns a copy of what's left
+// (made with CopyImage, so it's the same type as img if that's an *ebiten.Image, *image.RGBA, or *image.NRGBA, and an *image.RGBA otherwise),
+// with its top-left corner at (0, 0). Trimmed *ebiten.Images are created with mipmaps, like ebiten.NewImage's; use TrimTransparentMipmaps to choose.
+// It also returns the offset of the trimmed image's top-left corner from img's top-left corner, and img's original size, so that you can
+// draw the trimmed frames of an animation at the same position the untrimmed frames would have been at.
+// If every pixel is <= threshold, trimmed is nil and offset is (0, 0).
+// *image.RGBA and *image.NRGBA images are scanned straight from their pixel buffers, stopping at the first non-transparent pixel from each side.
+func TrimTransparent(img image.Image, threshold byte) (trimmed image.Image, offset image.Point, originalSize image.Point) {
+	return TrimTransparentMipmaps(img, threshold, true)
+}
+
+// TrimTransparentMipmaps is TrimTransparent, but if img is an *ebiten.Image, mipmaps says whether the trimmed copy is created with mipmaps,
+// like in CopyImage.
+func TrimTransparentMipmaps(img image.Image, threshold byte, mipmaps bool) (trimmed image.Image, offset image.Point, originalSize image.Point) {
+	img = unwrapSnapshot(img)
+	bounds := img.Bounds()
+	originalSize = bounds.Size()
+	var rect image.Rectangle
+	switch xImg := img.(type) {
+	case *image.RGBA:
+		rect = opaqueBounds(xImg.Pix, xImg.Stride, 4, 3, bounds.Dx(), bounds.Dy(), threshold)
+	case *image.NRGBA:
+		rect = opaqueBounds(xImg.Pix, xImg.Stride, 4, 3, bounds.Dx(), bounds.Dy(), threshold)
+	default:
+		alpha, width, height := alphaValues(img)
+		rect = opaqueBounds(alpha, width, 1, 0, width, height, threshold)
+	}
+	if rect.Empty() {
+		return nil, image.Point{}, originalSize
+	}
+	offset = rect.Min
+	if rect.Size() == originalSize {
+		return CopyImage(img, mipmaps), offset, originalSize
+	}
+	sImg, ok := img.(subImager)
+	if !ok {
+		// CopyImage makes an *image.RGBA for this anyways, so we copy the whole thing and trim the copy.
+		sImg = CopyImage(img, mipmaps).(*image.RGBA)
+		bounds = sImg.Bounds()
+	}
+	trimmed = CopyImage(sImg.SubImage(rect.Add(bounds.Min)), mipmaps)
+	return
+}
+
+// subImager is implemented by *ebiten.Image and most of the image types in the standard library.
+type subImager interface {
+	image.Image
+	SubImage(r image.Rectangle) image.Image
+}
+
+// opaqueBounds returns the smallest rectangle (relative to the top-left corner) containing every pixel whose alpha is greater than threshold,
+// or an empty rectangle if there aren't any. pix holds width x height pixels, each pixelSize bytes long, with alpha at alphaOffset within each pixel,
+// and with rows starting every stride bytes.
+func opaqueBounds(pix []byte, stride, pixelSize, alphaOffset, width, height int, threshold byte) image.Rectangle {
+	rowHasOpaque := func(y, left, right int) bool {
+		idx := y*stride + left*pixelSize + alphaOffset
+		for x := left; x < right; x++ {
+			if pix[idx] > threshold {
+				return true
+			}
+			idx += pixelSize
+		}
+		return false
+	}
+	colHasOpaque := func(x, top, bottom int) bool {
+		idx := top*stride + x*pixelSize + alphaOffset
+		for y := top; y < bottom; y++ {
+			if pix[idx] > threshold {
+				return true
+			}
+			idx += stride
+		}
+		return false
+	}
+	top := 0
+	for top < height && !rowHasOpaque(top, 0, width) {
+		top++
+	}
+	if top == height {
+		return image.Rectangle{}
+	}
+	bottom := height
+	for !rowHasOpaque(bottom-1, 0, width) {
+		bottom--
+	}
+	left := 0
+	for !colHasOpaque(left, top, bottom) {
+		left++
+	}
+	right := width
+	for !colHasOpaque(right-1, top, bottom) {
+		right--
+	}
+	return image.Rect(left, top, right, bottom)
+}
@@ -0,0 +1,93 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TrimTransparent(t *testing.T) {
+	ass := assert.New(t)
+	// a faint pixel at (1, 1) and a solid 3x2 block at (5, 4)
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 12))
+	img.SetNRGBA(1, 1, color.NRGBA{G: 255, A: 8})
+	for y := 4; y < 6; y++ {
+		for x := 5; x < 8; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: byte(x), G: byte(y), A: 255})
+		}
+	}
+
+	trimmed, offset, size := frostutil.TrimTransparent(img, 0)
+	require.IsType(t, &image.NRGBA{}, trimmed)
+	ass.Equal(image.Rect(0, 0, 7, 5), trimmed.Bounds())
+	ass.Equal(image.Pt(1, 1), offset)
+	ass.Equal(image.Pt(16, 12), size)
+
+	trimmed, offset, size = frostutil.TrimTransparent(img, 8)
+	require.IsType(t, &image.NRGBA{}, trimmed)
+	ass.Equal(image.Rect(0, 0, 3, 2), trimmed.Bounds())
+	ass.Equal(image.Pt(5, 4), offset)
+	ass.Equal(image.Pt(16, 12), size)
+	ass.Equal(img.NRGBAAt(5, 4), trimmed.(*image.NRGBA).NRGBAAt(0, 0))
+	ass.Equal(img.NRGBAAt(7, 5), trimmed.(*image.NRGBA).NRGBAAt(2, 1))
+
+	// RGBA images are trimmed from their pixel buffers too, and offsets are relative to the image's top-left corner
+	rgba := image.NewRGBA(image.Rect(0, 0, 16, 12))
+	rgba.Set(3, 7, color.RGBA{B: 9, A: 9})
+	rgba.Set(4, 9, color.RGBA{B: 9, A: 9})
+	sub := rgba.SubImage(image.Rect(2, 2, 10, 10))
+	trimmed, offset, size = frostutil.TrimTransparent(sub, 0)
+	require.IsType(t, &image.RGBA{}, trimmed)
+	ass.Equal(image.Rect(0, 0, 2, 3), trimmed.Bounds())
+	ass.Equal(image.Pt(1, 5), offset)
+	ass.Equal(image.Pt(8, 8), size)
+	ass.Equal(color.RGBA{B: 9, A: 9}, trimmed.(*image.RGBA).RGBAAt(1, 2))
+
+	// other image types are returned as *image.RGBA
+	trimmed, offset, _ = frostutil.TrimTransparent(struct{ image.Image }{img}, 8)
+	require.IsType(t, &image.RGBA{}, trimmed)
+	ass.Equal(image.Rect(0, 0, 3, 2), trimmed.Bounds())
+	ass.Equal(image.Pt(5, 4), offset)
+	alpha := image.NewAlpha(image.Rect(0, 0, 4, 4))
+	alpha.SetAlpha(2, 1, color.Alpha{A: 100})
+	trimmed, offset, _ = frostutil.TrimTransparent(alpha, 0)
+	require.IsType(t, &image.RGBA{}, trimmed)
+	ass.Equal(image.Rect(0, 0, 1, 1), trimmed.Bounds())
+	ass.Equal(image.Pt(2, 1), offset)
+
+	// nothing left
+	trimmed, offset, size = frostutil.TrimTransparent(img, 255)
+	ass.Nil(trimmed)
+	ass.Equal(image.Point{}, offset)
+	ass.Equal(image.Pt(16, 12), size)
+}
+
+func Test_TrimTransparentEImage(t *testing.T) {
+	frostutil.QueueUpdateTest(t, test_TrimTransparentEImage)
+}
+
+func test_TrimTransparentEImage(t *testing.T) {
+	ass := assert.New(t)
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 12))
+	img.SetNRGBA(1, 1, color.NRGBA{G: 255, A: 8})
+	img.SetNRGBA(5, 4, color.NRGBA{R: 5, A: 255})
+	img.SetNRGBA(7, 5, color.NRGBA{R: 7, G: 5, A: 255})
+	eImg := frostutil.NewEImageFromImage(img, false)
+	// the trimmed image is a copy made from the *ebiten.Image's SubImage
+	trimmed, offset, size := frostutil.TrimTransparent(eImg, 8)
+	require.IsType(t, &ebiten.Image{}, trimmed)
+	ass.Equal(image.Rect(0, 0, 3, 2), trimmed.Bounds())
+	ass.Equal(image.Pt(5, 4), offset)
+	ass.Equal(image.Pt(16, 12), size)
+	ass.Equal(color.RGBA{R: 7, G: 5, A: 255}, trimmed.At(2, 1))
+
+	trimmed, _, _ = frostutil.TrimTransparentMipmaps(eImg, 8, false)
+	if ass.IsType(&ebiten.Image{}, trimmed) {
+		ass.Equal(image.Rect(0, 0, 3, 2), trimmed.Bounds())
+	}
+}
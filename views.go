@@ -0,0 +1,95 @@
+package frostutil
+
+import (
+	"image"
+	"image/color"
+)
+
+// NRGBAView presents an *image.RGBA's pixels as straight (not alpha-premultiplied) colors without copying them. At converts each pixel
+// with UnmultiplyAlphaBytes when it's read, so it returns color.NRGBAs, and changes to the *image.RGBA are seen right away.
+// It's an image.Image, so it can be passed to anything that wants NRGBA colors (like png.Encode) instead of a copy made with NewNRGBAFromImage.
+type NRGBAView struct {
+	Source *image.RGBA
+}
+
+// NewNRGBAView returns an NRGBAView of img.
+func NewNRGBAView(img *image.RGBA) *NRGBAView {
+	return &NRGBAView{Source: img}
+}
+
+// ColorModel returns color.NRGBAModel.
+func (v *NRGBAView) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+// Bounds returns the bounds of the *image.RGBA.
+func (v *NRGBAView) Bounds() image.Rectangle {
+	return v.Source.Rect
+}
+
+// At returns the color of the pixel at (x, y) as a color.NRGBA.
+func (v *NRGBAView) At(x, y int) color.Color {
+	return v.NRGBAAt(x, y)
+}
+
+// NRGBAAt returns the color of the pixel at (x, y), with the alpha premultiplication removed by UnmultiplyAlphaBytes.
+// It returns transparent black for pixels outside the bounds.
+func (v *NRGBAView) NRGBAAt(x, y int) (c color.NRGBA) {
+	if !(image.Point{x, y}.In(v.Source.Rect)) {
+		return
+	}
+	idx := v.Source.PixOffset(x, y)
+	pix := v.Source.Pix[idx : idx+4 : idx+4]
+	c.R, c.G, c.B, c.A = UnmultiplyAlphaBytes(pix[0], pix[1], pix[2], pix[3])
+	return
+}
+
+// SubImage returns an NRGBAView of the part of the *image.RGBA inside r, which shares its pixels.
+func (v *NRGBAView) SubImage(r image.Rectangle) image.Image {
+	return NewNRGBAView(v.Source.SubImage(r).(*image.RGBA))
+}
+
+// RGBAView presents an *image.NRGBA's pixels as alpha-premultiplied colors without copying them. At converts each pixel
+// with MultiplyAlphaBytesPreserveColors when it's read, so it returns color.RGBAs, and changes to the *image.NRGBA are seen right away.
+// Like MultiplyAlphaBytesPreserveColors, it keeps the color components of pixels whose alpha component is zero, so they can be
+// written into an *ebiten.Image (with SlowImageCopy, for instance) without losing them.
+type RGBAView struct {
+	Source *image.NRGBA
+}
+
+// NewRGBAView returns an RGBAView of img.
+func NewRGBAView(img *image.NRGBA) *RGBAView {
+	return &RGBAView{Source: img}
+}
+
+// ColorModel returns color.RGBAModel.
+func (v *RGBAView) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds returns the bounds of the *image.NRGBA.
+func (v *RGBAView) Bounds() image.Rectangle {
+	return v.Source.Rect
+}
+
+// At returns the color of the pixel at (x, y) as a color.RGBA.
+func (v *RGBAView) At(x, y int) color.Color {
+	return v.RGBAAt(x, y)
+}
+
+// RGBAAt returns the color of the pixel at (x, y), alpha-premultiplied by MultiplyAlphaBytesPreserveColors.
+// It returns transparent black for pixels outside the bounds.
+func (v *RGBAView) RGBAAt(x, y int) (c color.RGBA) {
+	if !(image.Point{x, y}.In(v.Source.Rect)) {
+		return
+	}
+	idx := v.Source.PixOffset(x, y)
+	pix := v.Source.Pix[idx : idx+4 : idx+4]
+	c.R, c.G, c.B, c.A = MultiplyAlphaBytesPreserveColors(pix[0], pix[1], pix[2], pix[3])
+	return
+}
+
+// SubImage returns an RGBAView of the part of the *image.NRGBA inside r, which shares its pixels.
+func (v *RGBAView) SubImage(r image.Rectangle) image.Image {
+	return NewRGBAView(v.Source.SubImage(r).(*image.NRGBA))
+}
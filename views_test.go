@@ -0,0 +1,68 @@
+package frostutil_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/amanitaverna/frostutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NRGBAView(t *testing.T) {
+	ass := assert.New(t)
+	rgba := frostutil.CopyImage(GetTestImageRGBA(Alpha_DiagonalGradient), false).(*image.RGBA)
+	view := frostutil.NewNRGBAView(rgba)
+	ass.Equal(rgba.Bounds(), view.Bounds())
+	ass.Equal(color.NRGBAModel, view.ColorModel())
+	// it gives the same colors as a converted copy, including the hidden colors of transparent pixels
+	converted := frostutil.NewNRGBAFromImage(rgba)
+	for y := 0; y < testImgHeight; y++ {
+		for x := 0; x < testImgWidth; x++ {
+			if !ass.Equal(converted.NRGBAAt(x, y), view.At(x, y), "(%v, %v)", x, y) {
+				return
+			}
+		}
+	}
+	ass.Equal(color.NRGBA{}, view.NRGBAAt(-1, 0))
+	ass.Equal(color.NRGBA{}, view.NRGBAAt(0, testImgHeight))
+
+	// it doesn't copy the pixels
+	rgba.SetRGBA(10, 20, color.RGBA{0x40, 0x20, 0, 0x80})
+	var expected color.NRGBA
+	expected.R, expected.G, expected.B, expected.A = frostutil.UnmultiplyAlphaBytes(0x40, 0x20, 0, 0x80)
+	ass.Equal(expected, view.NRGBAAt(10, 20))
+
+	sub := view.SubImage(image.Rect(5, 15, 30, 40))
+	ass.Equal(image.Rect(5, 15, 30, 40), sub.Bounds())
+	ass.Equal(view.At(10, 20), sub.At(10, 20))
+	ass.Equal(color.NRGBA{}, sub.At(4, 20))
+}
+
+func Test_RGBAView(t *testing.T) {
+	ass := assert.New(t)
+	nrgba := GetTestImageNRGBA(Alpha_HorizontalGradient).(*image.NRGBA)
+	view := frostutil.NewRGBAView(nrgba)
+	ass.Equal(nrgba.Bounds(), view.Bounds())
+	ass.Equal(color.RGBAModel, view.ColorModel())
+	for y := 0; y < testImgHeight; y++ {
+		for x := 0; x < testImgWidth; x++ {
+			c := nrgba.NRGBAAt(x, y)
+			var expected color.RGBA
+			expected.R, expected.G, expected.B, expected.A = frostutil.MultiplyAlphaBytesPreserveColors(c.R, c.G, c.B, c.A)
+			if !ass.Equal(expected, view.At(x, y), "(%v, %v)", x, y) {
+				return
+			}
+		}
+	}
+	// transparent pixels keep their colors
+	ass.Equal(color.RGBA{0, 7, 3, 0}, view.RGBAAt(0, 7))
+
+	nrgba.SetNRGBA(3, 4, color.NRGBA{0xff, 0x80, 0, 0x80})
+	ass.Equal(color.RGBA{0x80, 0x40, 0, 0x80}, view.RGBAAt(3, 4))
+
+	sub := view.SubImage(image.Rect(2, 2, 8, 8))
+	ass.Equal(image.Rect(2, 2, 8, 8), sub.Bounds())
+	ass.Equal(view.At(3, 4), sub.At(3, 4))
+	ass.Equal(color.RGBA{}, sub.At(8, 4))
+}